@@ -0,0 +1,119 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit records the tokens issued by the authenticators, and the reason they were asked for.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Event is a token returned by an authenticator. It never carries the token or the secret.
+type Event struct {
+	// Time the token was returned at.
+	Time time.Time `json:"time"`
+	// Caller is the component which asked for the token.
+	Caller string `json:"caller"`
+	// Reason is the reasonForCall given by the caller.
+	Reason string `json:"reason"`
+	// AuthType is the type of credentials used to fetch the token (iam or pod-identity).
+	AuthType string `json:"authType"`
+	// Subject is the sub claim of the token.
+	Subject string `json:"subject"`
+	// IAMID is the iam_id claim of the token.
+	IAMID string `json:"iamID"`
+	// Expiry is the time the token expires at.
+	Expiry time.Time `json:"expiry"`
+	// CacheHit is true if the token in cache was returned, false if a fresh token was fetched from IAM.
+	CacheHit bool `json:"cacheHit"`
+}
+
+// Sink records the audit events. Implementations must be safe for concurrent use.
+type Sink interface {
+	Record(event Event) error
+}
+
+// NoopSink ...
+type NoopSink struct{}
+
+// Record ...
+func (NoopSink) Record(event Event) error {
+	return nil
+}
+
+// FileSink appends the events to a file, one JSON object per line.
+type FileSink struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewFileSink opens (or creates) the file at path for appending the events.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Record ...
+func (fs *FileSink) Record(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	_, err = fs.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (fs *FileSink) Close() error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.file.Close()
+}
+
+// ZapSink logs the events using a zap logger.
+type ZapSink struct {
+	logger *zap.Logger
+}
+
+// NewZapSink ...
+func NewZapSink(logger *zap.Logger) *ZapSink {
+	return &ZapSink{logger: logger}
+}
+
+// Record ...
+func (zs *ZapSink) Record(event Event) error {
+	zs.logger.Info("IAM token issued",
+		zap.Time("time", event.Time),
+		zap.String("caller", event.Caller),
+		zap.String("reason", event.Reason),
+		zap.String("auth-type", event.AuthType),
+		zap.String("subject", event.Subject),
+		zap.String("iam-id", event.IAMID),
+		zap.Time("expiry", event.Expiry),
+		zap.Bool("cache-hit", event.CacheHit))
+	return nil
+}
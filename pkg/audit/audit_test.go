@@ -0,0 +1,91 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestEvent(cacheHit bool) Event {
+	now := time.Now().UTC().Truncate(time.Second)
+	return Event{
+		Time:     now,
+		Caller:   "vpc-block-csi-driver",
+		Reason:   "CreateVolume",
+		AuthType: "iam",
+		Subject:  "ServiceId-1234",
+		IAMID:    "iam-ServiceId-1234",
+		Expiry:   now.Add(time.Hour),
+		CacheHit: cacheHit,
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	events := []Event{newTestEvent(false), newTestEvent(true)}
+
+	// Events are appended to the existing file.
+	for _, event := range events {
+		sink, err := NewFileSink(path)
+		assert.Nil(t, err)
+		assert.Nil(t, sink.Record(event))
+		assert.Nil(t, sink.Close())
+	}
+
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	var recorded []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &event))
+		recorded = append(recorded, event)
+	}
+	assert.Equal(t, events, recorded)
+
+	_, err = NewFileSink(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.NotNil(t, err)
+}
+
+func TestZapSink(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	sink := NewZapSink(zap.New(core))
+
+	event := newTestEvent(true)
+	assert.Nil(t, sink.Record(event))
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "vpc-block-csi-driver", fields["caller"])
+	assert.Equal(t, "CreateVolume", fields["reason"])
+	assert.Equal(t, "iam", fields["auth-type"])
+	assert.Equal(t, "ServiceId-1234", fields["subject"])
+	assert.Equal(t, "iam-ServiceId-1234", fields["iam-id"])
+	assert.Equal(t, true, fields["cache-hit"])
+}
@@ -17,8 +17,9 @@
 package authenticator
 
 import (
+	"context"
+
 	"github.com/IBM/go-sdk-core/v5/core"
-	"github.com/IBM/secret-utils-lib/pkg/token"
 	"github.com/IBM/secret-utils-lib/pkg/utils"
 	"go.uber.org/zap"
 )
@@ -26,10 +27,8 @@ import (
 // APIKeyAuthenticator ...
 type APIKeyAuthenticator struct {
 	authenticator     *core.IamAuthenticator
-	logger            *zap.Logger
 	isSecretEncrypted bool
-	token             string
-	userProvidedURL   bool
+	tokenManager
 }
 
 // NewIamAuthenticator ...
@@ -37,67 +36,18 @@ func NewIamAuthenticator(apikey string, logger *zap.Logger) *APIKeyAuthenticator
 	aa := new(APIKeyAuthenticator)
 	aa.authenticator = new(core.IamAuthenticator)
 	aa.authenticator.ApiKey = apikey
-	aa.logger = logger
+	aa.tokenManager = newTokenManager(logger, utils.IAM)
 	return aa
 }
 
 // GetToken ...
-func (aa *APIKeyAuthenticator) GetToken(freshTokenRequired bool) (string, uint64, error) {
-	var err error
-	var tokenlifetime uint64
-
-	if !freshTokenRequired {
-		// Fetching token life time of the token in cache
-		tokenlifetime, err = token.CheckTokenLifeTime(aa.token)
-		if err == nil {
-			aa.logger.Info("Fetched iam token from cache", zap.Uint64("token-life-time-in-seconds", tokenlifetime))
-			return aa.token, tokenlifetime, nil
-		}
-	}
-
-	var tokenResponse *core.IamTokenServerResponse
-	err = retry(aa.logger, func() error {
-		tokenResponse, err = aa.authenticator.RequestToken()
-		return err
-	})
-
-	if err != nil {
-		// If the error is not related to timeout or if the token exchange URL is provided by user, return error.
-		if !isTimeout(err) || aa.userProvidedURL {
-			return "", tokenlifetime, utils.Error{Description: "Error fetching iam token using api key", BackendError: err.Error()}
-		}
-
-		// By default authenticator uses private IAM URL, setting it to public
-		setPublicIAMURL(aa)
-
-		// Retry fetching IAM token after switching from private to public IAM URL.
-		aa.logger.Info("Updated IAM URL from private to public, retrying to fetch IAM token")
-		err = retry(aa.logger, func() error {
-			tokenResponse, err = aa.authenticator.RequestToken()
-			return err
-		})
-
-		// Resetting to private IAM URL.
-		setPrivateIAMURL(aa)
-		if err != nil {
-			return "", tokenlifetime, utils.Error{Description: "Error fetching iam token using api key", BackendError: err.Error()}
-		}
-	}
-
-	if tokenResponse == nil {
-		aa.logger.Error("Token response received is empty")
-		return "", tokenlifetime, utils.Error{Description: utils.ErrEmptyTokenResponse}
-	}
-
-	tokenlifetime, err = token.CheckTokenLifeTime(tokenResponse.AccessToken)
-	if err != nil {
-		aa.logger.Error("Error fetching token lifetime for new token", zap.Error(err))
-		return "", tokenlifetime, utils.Error{Description: "Error fetching token lifetime", BackendError: err.Error()}
-	}
-	aa.token = tokenResponse.AccessToken
+func (aa *APIKeyAuthenticator) GetToken(freshTokenRequired bool, reasonForCall ...string) (string, uint64, error) {
+	return aa.GetTokenWithContext(context.Background(), freshTokenRequired, reasonForCall...)
+}
 
-	aa.logger.Info("Fetched fresh iam token", zap.Uint64("token-life-time-in-seconds", tokenlifetime))
-	return aa.token, tokenlifetime, nil
+// GetTokenWithContext is GetToken, recording the GetToken span as a child of the span in ctx.
+func (aa *APIKeyAuthenticator) GetTokenWithContext(ctx context.Context, freshTokenRequired bool, reasonForCall ...string) (string, uint64, error) {
+	return aa.getToken(ctx, aa, freshTokenRequired, reasonForCall, aa.authenticator.RequestToken, "Error fetching iam token using api key")
 }
 
 // GetSecret ...
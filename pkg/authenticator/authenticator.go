@@ -18,12 +18,16 @@
 package authenticator
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/IBM/secret-utils-lib/pkg/audit"
 	"github.com/IBM/secret-utils-lib/pkg/config"
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/metrics"
+	"github.com/IBM/secret-utils-lib/pkg/token"
 	"github.com/IBM/secret-utils-lib/pkg/utils"
 	"go.uber.org/zap"
 )
@@ -38,88 +42,187 @@ const (
 	// maxRetryAttempt ...
 	maxRetryAttempt = 9
 
+	// DefaultMinTokenLifetimePercent is the minimum remaining lifetime of the token in cache, as a percentage
+	// of its total lifetime, used when neither a token lifetime policy nor utils.TokenExpirydiff are set.
+	// For the 60 minutes IAM tokens, a fresh token is fetched when less than 6 minutes remain.
+	DefaultMinTokenLifetimePercent = 10
+
+	// DefaultNegativeCacheTTL is how long a rejection of the secret by IAM is cached by default.
+	DefaultNegativeCacheTTL = 5 * time.Minute
+
+	// initialRetryGap ...
+	initialRetryGap = 2 * time.Second
+
 	// maxRetryGap ...
-	maxRetryGap = 60
+	maxRetryGap = 60 * time.Second
 )
 
 // Authenticator ...
 type Authenticator interface {
-	GetToken(freshTokenRequired bool) (string, uint64, error)
+	GetToken(freshTokenRequired bool, reasonForCall ...string) (string, uint64, error)
+	GetTokenWithContext(ctx context.Context, freshTokenRequired bool, reasonForCall ...string) (string, uint64, error)
 	GetSecret() string
 	SetSecret(secret string)
 	SetURL(url string, userProvided bool)
 	SetEncryption(bool)
 	IsSecretEncrypted() bool
+	SetRetryPolicy(policy RetryPolicy)
+	SetNegativeCacheTTL(ttl time.Duration)
+	SetMetricsRecorder(recorder metrics.Recorder, provider string)
+	SetAuditSink(sink audit.Sink, caller string)
+	GetTokenClaims() (*token.IAMClaims, error)
+	SetTokenLifetimePolicy(policy token.LifetimePolicy)
 	getURL() string
+	setAuthType(authType string)
 }
 
 // NewAuthenticator initializes the particular authenticator based on the configuration provided.
+//
+// Deprecated: optionalArgs only supports the SecretKey and ProviderType keys, use NewAuthenticatorWithOptions instead.
 func NewAuthenticator(logger *zap.Logger, kc k8s_utils.KubernetesClient, optionalArgs ...map[string]string) (Authenticator, string, error) {
-	logger.Info("Initializing authenticator")
-
-	// Check if secretKey or providerType is provided
-	var secretKeyName, providerName string
-	var secretKeyExists, providerExists bool
+	var opts Options
 	if len(optionalArgs) != 0 {
-		secretKeyName, secretKeyExists = optionalArgs[0][SecretKey]
-		providerName, providerExists = optionalArgs[0][ProviderType]
+		for key, value := range optionalArgs[0] {
+			switch key {
+			case SecretKey:
+				logger.Info("Key provided", zap.String("Key", value))
+				opts.SecretKey = value
+			case ProviderType:
+				opts.ProviderType = value
+			default:
+				logger.Warn("Ignoring unknown optional argument, expected - SecretKey or ProviderType", zap.String("argument", key))
+			}
+		}
 	}
 
-	// If a secretKey (key in the k8s secret) is provided, first look for the key in ibm-cloud-credentials
-	// If it is not found ibm-cloud-credentials, look for it in storage-secret-store
-	// If it is not found in either of the secrets, return error
-	if secretKeyExists {
-		logger.Info("Key provided", zap.String("Key", secretKeyName))
-		data, err := k8s_utils.GetSecretData(kc, utils.IBMCLOUD_CREDENTIALS_SECRET, secretKeyName)
-		if err == nil {
-			return initAuthenticatorForIBMCloudCredentials(logger, data)
-		}
+	return NewAuthenticatorWithOptions(logger, kc, opts)
+}
 
-		logger.Warn("Unable to fetch ibm-cloud-credentials, fetching from storage-secret-store", zap.Error(err))
-		data, err = k8s_utils.GetSecretData(kc, utils.STORAGE_SECRET_STORE_SECRET, secretKeyName)
-		if err != nil {
-			logger.Error("Error initializing authenticator", zap.Error(err))
-			return nil, "", err
-		}
-		logger.Info("Initialized authenticator", zap.String("secret-name", utils.STORAGE_SECRET_STORE_SECRET), zap.String("key-name", secretKeyName))
-		return NewIamAuthenticator(data, logger), utils.DEFAULT, nil
-	}
+// NewAuthenticatorWithOptions initializes the particular authenticator by reading the credential sources in the given order.
+func NewAuthenticatorWithOptions(logger *zap.Logger, kc k8s_utils.KubernetesClient, opts Options) (Authenticator, string, error) {
+	return NewAuthenticatorWithContext(context.Background(), logger, kc, opts)
+}
+
+// NewAuthenticatorWithContext is NewAuthenticatorWithOptions, recording a NewAuthenticator span in ctx,
+// with the secrets read and the credentials parsed as child spans.
+func NewAuthenticatorWithContext(ctx context.Context, logger *zap.Logger, kc k8s_utils.KubernetesClient, opts Options) (authenticator Authenticator, authType string, err error) {
+	ctx, span := utils.StartSpan(ctx, "NewAuthenticator", utils.AttrProvider.String(opts.providerType()))
+	defer func() {
+		span.SetAttributes(utils.AttrAuthType.String(authType))
+		utils.EndSpan(span, err)
+	}()
+
+	logger = logger.With(opts.LoggerFields...)
+	logger.Info("Initializing authenticator")
 
-	// If the secretKey is not provided,
-	// Read ibm-credentials.env key from ibm-cloud-credentials
-	data, err := k8s_utils.GetSecretData(kc, utils.IBMCLOUD_CREDENTIALS_SECRET, utils.CLOUD_PROVIDER_ENV)
-	if err == nil {
-		return initAuthenticatorForIBMCloudCredentials(logger, data)
+	if err = opts.Validate(); err != nil {
+		logger.Error("Invalid options provided", zap.Error(err))
+		return nil, "", err
 	}
 
-	// If ibm-cloud-credentials does not exist, read slclient.toml from storage-secret-store
-	logger.Warn("Unable to fetch ibm-cloud-credentials", zap.Error(err), zap.String("key-name", utils.CLOUD_PROVIDER_ENV))
-	data, err = k8s_utils.GetSecretData(kc, utils.STORAGE_SECRET_STORE_SECRET, utils.SECRET_STORE_FILE)
+	authenticator, authType, err = initAuthenticator(ctx, logger, kc, opts)
 	if err != nil {
-		logger.Error("Error initializing authenticator", zap.Error(err))
 		return nil, "", err
 	}
 
-	// If providerType is given, check for the same in storage secret store
-	if providerExists {
-		return initAuthenticatorForStorageSecretStore(logger, providerName, data)
+	authenticator.setAuthType(authType)
+	if opts.RetryPolicy != nil {
+		authenticator.SetRetryPolicy(*opts.RetryPolicy)
+	}
+	authenticator.SetNegativeCacheTTL(opts.negativeCacheTTL())
+	authenticator.SetMetricsRecorder(opts.Metrics, opts.providerType())
+	authenticator.SetAuditSink(opts.AuditSink, opts.auditCaller())
+	if opts.TokenLifetimePolicy != nil {
+		authenticator.SetTokenLifetimePolicy(*opts.TokenLifetimePolicy)
+	}
+	if opts.TokenExchangeURL != "" {
+		logger.Info("Using the token exchange URL provided", zap.String("url", opts.TokenExchangeURL))
+		authenticator.SetURL(opts.TokenExchangeURL, true)
 	}
-	return initAuthenticatorForStorageSecretStore(logger, utils.VPC, data)
+	return authenticator, authType, nil
 }
 
-// isProviderType ...
-func isProviderType(arg string) bool {
+// initAuthenticator reads the credentials and initializes the authenticator.
+func initAuthenticator(ctx context.Context, logger *zap.Logger, kc k8s_utils.KubernetesClient, opts Options) (Authenticator, string, error) {
+	// If k8s client is not provided (library used outside the cluster), read the credentials from environment variables
+	if kc.Clientset == nil {
+		logger.Info("k8s client not provided, reading credentials from environment")
+		return initAuthenticatorFromEnv(logger, opts.providerType())
+	}
+
+	// The first source which can be read is used, even if a later source also exists (for the default sources,
+	// ibm-cloud-credentials takes precedence over storage-secret-store). If the data read from it is invalid,
+	// the remaining sources are not tried. If none of the sources can be read, return error.
+	var sourcesErr CredentialSourcesError
+	for _, source := range opts.credentialSources() {
+		data, err := k8s_utils.GetSecretDataFromSourceWithContext(ctx, kc, source.SecretSource)
+		if err != nil {
+			logger.Warn("Unable to fetch credentials, trying the next source", zap.String("namespace", source.Namespace),
+				zap.String("secret-name", source.SecretName), zap.String("key-name", source.Key), zap.Error(err))
+			sourcesErr.Attempts = append(sourcesErr.Attempts, SourceAttempt{Source: source, Err: err})
+			continue
+		}
+
+		if opts.ProviderType != "" && source.Format != FormatSecretStore {
+			logger.Warn("Provider type is used only for slclient.toml, ignoring it", zap.String("provider", opts.ProviderType),
+				zap.String("secret-name", source.SecretName), zap.String("key-name", source.Key))
+		}
+
+		// Log the namespace the secret was actually read from
+		readSource := source
+		if readSource.Namespace == "" {
+			readSource.Namespace = kc.Namespace
+		}
+		authenticator, authType, err := initAuthenticatorForSource(ctx, logger, readSource, opts.providerType(), data)
+		if err != nil {
+			sourcesErr.Attempts = append(sourcesErr.Attempts, SourceAttempt{Source: source, Err: err})
+			logger.Error("Error initializing authenticator", zap.Error(sourcesErr))
+			return nil, "", sourcesErr
+		}
+		return authenticator, authType, nil
+	}
+
+	logger.Error("Error initializing authenticator", zap.Error(sourcesErr))
+	return nil, "", sourcesErr
+}
+
+// initAuthenticatorForSource initializes the authenticator based on the format of data read from the source.
+func initAuthenticatorForSource(ctx context.Context, logger *zap.Logger, source CredentialSource, providerName, data string) (authenticator Authenticator, authType string, err error) {
+	_, span := utils.StartSpan(ctx, "ParseCredentials", utils.AttrCredentialFormat.String(source.Format), utils.AttrSecretName.String(source.SecretName))
+	defer func() { utils.EndSpan(span, err) }()
+
+	switch source.Format {
+	case FormatIBMCloudCredentials:
+		return initAuthenticatorForIBMCloudCredentials(logger, source.SecretName, data)
+	case FormatSecretStore:
+		return initAuthenticatorForStorageSecretStore(logger.With(zap.String("namespace", source.Namespace), zap.String("secret-name", source.SecretName),
+			zap.String("key-name", source.Key)), providerName, data)
+	case FormatAPIKey:
+		logger.Info("Initialized authenticator", zap.String("namespace", source.Namespace), zap.String("secret-name", source.SecretName), zap.String("key-name", source.Key))
+		return NewIamAuthenticator(data, logger), utils.DEFAULT, nil
+	}
+	return nil, "", utils.Error{Description: fmt.Sprintf(utils.ErrUnknownCredentialFormat, source.Format)}
+}
+
+// IsProviderType checks if arg is one of the provider types in slclient.toml - vpc, bluemix, softlayer.
+func IsProviderType(arg string) bool {
 	return (arg == utils.VPC || arg == utils.Bluemix || arg == utils.Softlayer)
 }
 
 // initAuthenticatorForIBMCloudCredentials ...
-func initAuthenticatorForIBMCloudCredentials(logger *zap.Logger, data string) (Authenticator, string, error) {
+func initAuthenticatorForIBMCloudCredentials(logger *zap.Logger, secretName, data string) (Authenticator, string, error) {
 	credentialsmap, err := parseIBMCloudCredentials(logger, data)
 	if err != nil {
 		logger.Error("Error parsing credentials", zap.Error(err))
 		return nil, "", err
 	}
 
+	return initAuthenticatorFromCredentials(logger, credentialsmap, secretName)
+}
+
+// initAuthenticatorFromCredentials initializes the authenticator using validated credentials,
+// source is the name of the secret (or environment) from which the credentials were read.
+func initAuthenticatorFromCredentials(logger *zap.Logger, credentialsmap utils.Credentials, source string) (Authenticator, string, error) {
 	var authenticator Authenticator
 	var defaultSecret string
 	credentialType := credentialsmap[utils.IBMCLOUD_AUTHTYPE]
@@ -132,11 +235,13 @@ func initAuthenticatorForIBMCloudCredentials(logger *zap.Logger, data string) (A
 		authenticator = NewComputeIdentityAuthenticator(defaultSecret, logger)
 	}
 
-	logger.Info("Successfully initialized authenticator", zap.String("secret-name", utils.IBMCLOUD_CREDENTIALS_SECRET), zap.String("auth-type", credentialType))
+	logger.Info("Successfully initialized authenticator", zap.String("secret-name", source), zap.String("auth-type", credentialType),
+		zap.Object("credentials", credentialsmap))
 	return authenticator, credentialType, nil
 }
 
-// initAuthenticatorForStorageSecretStore ...
+// initAuthenticatorForStorageSecretStore initializes the authenticator using slclient.toml,
+// logger carries the source (secret or file) from which data was read.
 func initAuthenticatorForStorageSecretStore(logger *zap.Logger, providerName, data string) (Authenticator, string, error) {
 	conf, err := config.ParseConfig(logger, data)
 	if err != nil {
@@ -144,38 +249,27 @@ func initAuthenticatorForStorageSecretStore(logger *zap.Logger, providerName, da
 		return nil, "", err
 	}
 
-	var encryption bool
-	var apiKey string
-	switch providerName {
-	case utils.VPC:
-		encryption = conf.VPC.Encryption
-		apiKey = conf.VPC.G2APIKey
-	case utils.Bluemix:
-		encryption = conf.Bluemix.Encryption
-		apiKey = conf.Bluemix.IamAPIKey
-	case utils.Softlayer:
-		apiKey = conf.Softlayer.SoftlayerAPIKey
-	default:
+	if !IsProviderType(providerName) {
 		return nil, "", utils.Error{Description: utils.ErrInvalidProviderType}
 	}
 
-	if apiKey == "" {
-		logger.Error("Empty api key read from the secret", zap.String("provider", providerName))
-		return nil, "", utils.Error{Description: utils.ErrAPIKeyNotProvided}
+	if err = conf.Validate(providerName); err != nil {
+		logger.Error("Section or api key of the provider missing in the secret", zap.String("provider", providerName), zap.Error(err))
+		return nil, "", utils.Error{Description: err.Error(), Code: utils.InvalidCredentials, Err: err}
 	}
 
-	authenticator := NewIamAuthenticator(apiKey, logger)
-	authenticator.SetEncryption(encryption)
-	logger.Info("Successfully initialized authenticator", zap.String("secret-name", utils.STORAGE_SECRET_STORE_SECRET), zap.String("auth-type", utils.DEFAULT))
+	authenticator := NewIamAuthenticator(conf.APIKey(providerName), logger)
+	authenticator.SetEncryption(conf.Encryption(providerName))
+	logger.Info("Successfully initialized authenticator", zap.String("auth-type", utils.DEFAULT))
 	return authenticator, utils.DEFAULT, nil
 }
 
 // parseIBMCloudCredentials: parses the given data into key value pairs
 // a map of credentials.
-func parseIBMCloudCredentials(logger *zap.Logger, data string) (map[string]string, error) {
+func parseIBMCloudCredentials(logger *zap.Logger, data string) (utils.Credentials, error) {
 
 	credentials := strings.Split(data, "\n")
-	credentialsmap := make(map[string]string)
+	credentialsmap := make(utils.Credentials)
 	for _, credential := range credentials {
 		if credential == "" {
 			continue
@@ -190,32 +284,38 @@ func parseIBMCloudCredentials(logger *zap.Logger, data string) (map[string]strin
 
 	if len(credentialsmap) == 0 {
 		logger.Error("Credentials provided are not in the expected format")
-		return nil, utils.Error{Description: utils.ErrInvalidCredentialsFormat}
+		return nil, utils.Error{Description: utils.ErrInvalidCredentialsFormat, Code: utils.InvalidCredentials}
 	}
 
+	return validateIBMCloudCredentials(logger, credentialsmap)
+}
+
+// validateIBMCloudCredentials checks that the credentials map carries a known auth type
+// and the secret (api key / profile ID) required by it.
+func validateIBMCloudCredentials(logger *zap.Logger, credentialsmap utils.Credentials) (utils.Credentials, error) {
 	// validating credentials
 	credentialType, ok := credentialsmap[utils.IBMCLOUD_AUTHTYPE]
 	if !ok {
-		logger.Error("IBMCLOUD_AUTHTYPE is undefined, expected - IAM or PODIDENTITY")
-		return nil, utils.Error{Description: utils.ErrAuthTypeUndefined}
+		logger.Error("IBMCLOUD_AUTHTYPE is undefined, expected - IAM or PODIDENTITY", zap.Object("credentials", credentialsmap))
+		return nil, utils.Error{Description: utils.ErrAuthTypeUndefined, Code: utils.InvalidCredentials}
 	}
 
 	if credentialType != utils.IAM && credentialType != utils.PODIDENTITY {
 		logger.Error("Credential type provided is unknown", zap.String("Credential type", credentialType))
-		return nil, utils.Error{Description: fmt.Sprintf(utils.ErrUnknownCredentialType, credentialType)}
+		return nil, utils.Error{Description: fmt.Sprintf(utils.ErrUnknownCredentialType, credentialType), Code: utils.InvalidCredentials}
 	}
 
 	if credentialType == utils.IAM {
 		if secret, ok := credentialsmap[utils.IBMCLOUD_APIKEY]; !ok || secret == "" {
-			logger.Error("API key is empty")
-			return nil, utils.Error{Description: utils.ErrAPIKeyNotProvided}
+			logger.Error("API key is empty", zap.Object("credentials", credentialsmap))
+			return nil, utils.Error{Description: utils.ErrAPIKeyNotProvided, Code: utils.InvalidCredentials}
 		}
 	}
 
 	if credentialType == utils.PODIDENTITY {
 		if secret, ok := credentialsmap[utils.IBMCLOUD_PROFILEID]; !ok || secret == "" {
-			logger.Error("Profile ID is empty")
-			return nil, utils.Error{Description: utils.ErrProfileIDNotProvided}
+			logger.Error("Profile ID is empty", zap.Object("credentials", credentialsmap))
+			return nil, utils.Error{Description: utils.ErrProfileIDNotProvided, Code: utils.InvalidCredentials}
 		}
 	}
 
@@ -251,8 +351,46 @@ func setPrivateIAMURL(auth Authenticator) {
 	}
 }
 
-// retry ...
-func retry(logger *zap.Logger, retryfunc func() error) error {
+// sleep waits between two attempts, overridden in tests.
+var sleep = time.Sleep
+
+// retry calls retryfunc until it succeeds, fails with an error other than a timeout, or the attempts are exhausted.
+// If policy is nil, the default schedule is used, see retryWithDefaultSchedule.
+func retry(logger *zap.Logger, policy *RetryPolicy, retryfunc func() error) error {
+	if policy == nil {
+		return retryWithDefaultSchedule(logger, retryfunc)
+	}
+
+	retryGap := policy.InitialGap
+	var err error
+
+	for retryAttempt := 0; retryAttempt < policy.MaxAttempts; retryAttempt++ {
+		err = retryfunc()
+		if err == nil {
+			return err
+		}
+
+		logger.Error("Error fetching fresh token", zap.Error(err), zap.Int("AttemptNo", retryAttempt+1))
+		// isTimeout checks whether the error is due to timeout.
+		// If the error is anything else other than timeout, do not retry (hence returning from the retry function)
+		if !isTimeout(err) || retryAttempt == policy.MaxAttempts-1 {
+			return err
+		}
+
+		sleep(retryGap)
+		retryGap = retryGap * 2
+		if retryGap > policy.MaxGap {
+			retryGap = policy.MaxGap
+		}
+	}
+
+	return err
+}
+
+// retryWithDefaultSchedule is retry when no retry policy is set.
+// The wait also follows the last attempt, and once the attempts are exhausted the timeout is not returned:
+// the caller is left without a token response.
+func retryWithDefaultSchedule(logger *zap.Logger, retryfunc func() error) error {
 
 	// total retry duration amounts to:
 	// 2 + 4 + 8 + 16 + 32 + (60*4) = 302 seconds (5 minutes approximately)
@@ -263,9 +401,7 @@ func retry(logger *zap.Logger, retryfunc func() error) error {
 	// 5th retry - 32 seconds
 	// 6th ... 9th retry - 60 seconds
 
-	// TODO: maxRetryAttempt and maxRetryGap needs to be made configurable.
-	retryGap := 2
-	var err error
+	retryGap := initialRetryGap
 
 	for retryAttempt := 0; retryAttempt < maxRetryAttempt; retryAttempt++ {
 		err := retryfunc()
@@ -280,12 +416,12 @@ func retry(logger *zap.Logger, retryfunc func() error) error {
 			return err
 		}
 
-		time.Sleep(time.Second * time.Duration(retryGap))
+		sleep(retryGap)
 		retryGap = retryGap * 2
 		if retryGap > maxRetryGap {
 			retryGap = maxRetryGap
 		}
 	}
 
-	return err
+	return nil
 }
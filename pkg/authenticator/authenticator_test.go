@@ -0,0 +1,535 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/config"
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/token"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewAuthenticatorFromEnv(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	testcases := []struct {
+		testcasename     string
+		env              map[string]string
+		providerType     string
+		expectedAuthType string
+		expectedSecret   string
+		expectError      bool
+	}{
+		{
+			testcasename:     "API key provided in environment",
+			env:              map[string]string{utils.IBMCLOUD_AUTHTYPE: utils.IAM, utils.IBMCLOUD_APIKEY: "apikey"},
+			expectedAuthType: utils.IAM,
+			expectedSecret:   "apikey",
+		},
+		{
+			testcasename:     "Profile ID provided in environment",
+			env:              map[string]string{utils.IBMCLOUD_AUTHTYPE: utils.PODIDENTITY, utils.IBMCLOUD_PROFILEID: "profile"},
+			expectedAuthType: utils.PODIDENTITY,
+			expectedSecret:   "profile",
+		},
+		{
+			testcasename: "Empty API key provided in environment",
+			env:          map[string]string{utils.IBMCLOUD_AUTHTYPE: utils.IAM},
+			expectError:  true,
+		},
+		{
+			testcasename: "Unknown auth type provided in environment",
+			env:          map[string]string{utils.IBMCLOUD_AUTHTYPE: "invalid"},
+			expectError:  true,
+		},
+		{
+			testcasename:     "slclient.toml path provided in environment",
+			env:              map[string]string{utils.SECRET_CONFIG_PATH: filepath.Join(pwd, "..", "..", "secrets/storage-secret-store/slclient.toml")},
+			providerType:     utils.Bluemix,
+			expectedAuthType: utils.DEFAULT,
+			expectedSecret:   "bluemix-api-key",
+		},
+		{
+			testcasename: "slclient.toml path does not exist",
+			env:          map[string]string{utils.SECRET_CONFIG_PATH: filepath.Join(pwd, "does-not-exist.toml")},
+			expectError:  true,
+		},
+		{
+			testcasename: "No credentials in environment",
+			env:          map[string]string{},
+			expectError:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			for _, key := range []string{utils.IBMCLOUD_AUTHTYPE, utils.IBMCLOUD_APIKEY, utils.IBMCLOUD_PROFILEID, utils.SECRET_CONFIG_PATH} {
+				t.Setenv(key, testcase.env[key])
+			}
+
+			var optionalArgs []map[string]string
+			if testcase.providerType != "" {
+				optionalArgs = append(optionalArgs, map[string]string{ProviderType: testcase.providerType})
+			}
+
+			authenticator, authType, err := NewAuthenticator(logger, k8s_utils.KubernetesClient{}, optionalArgs...)
+			if testcase.expectError {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedAuthType, authType)
+			assert.Equal(t, testcase.expectedSecret, authenticator.GetSecret())
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialGap: time.Millisecond, MaxGap: 2 * time.Millisecond}
+	testcases := []struct {
+		testcasename     string
+		errs             []error
+		expectedAttempts int
+		expectError      bool
+	}{
+		{
+			testcasename:     "Successful first attempt",
+			errs:             []error{nil},
+			expectedAttempts: 1,
+		},
+		{
+			testcasename:     "Successful after timeouts",
+			errs:             []error{errors.New("Client.Timeout exceeded"), errors.New("Client.Timeout exceeded"), nil},
+			expectedAttempts: 3,
+		},
+		{
+			testcasename:     "Error other than timeout is not retried",
+			errs:             []error{errors.New("bad request")},
+			expectedAttempts: 1,
+			expectError:      true,
+		},
+		{
+			testcasename:     "Attempts exhausted",
+			errs:             []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout"), nil},
+			expectedAttempts: 3,
+			expectError:      true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			var attempts int
+			err := retry(logger, &policy, func() error {
+				attempts++
+				return testcase.errs[attempts-1]
+			})
+			assert.Equal(t, testcase.expectedAttempts, attempts)
+			assert.Equal(t, testcase.expectError, err != nil)
+		})
+	}
+}
+
+func TestRetryDefaultSchedule(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	var gaps []time.Duration
+	defer func(s func(time.Duration)) { sleep = s }(sleep)
+	sleep = func(gap time.Duration) { gaps = append(gaps, gap) }
+
+	// Every attempt times out, the wait follows every attempt and no error is returned.
+	var attempts int
+	err := retry(logger, nil, func() error {
+		attempts++
+		return errors.New("Client.Timeout exceeded")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 9, attempts)
+	second := time.Second
+	assert.Equal(t, []time.Duration{2 * second, 4 * second, 8 * second, 16 * second, 32 * second, 60 * second, 60 * second, 60 * second, 60 * second}, gaps)
+
+	// Errors other than timeouts are returned without waiting.
+	gaps, attempts = nil, 0
+	err = retry(logger, nil, func() error {
+		attempts++
+		return errors.New("bad request")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.Empty(t, gaps)
+
+	// With a policy, there is no wait after the last attempt and its timeout is returned.
+	err = retry(logger, &RetryPolicy{MaxAttempts: 3, InitialGap: second, MaxGap: 90 * second}, func() error {
+		return errors.New("Client.Timeout exceeded")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, []time.Duration{second, 2 * second}, gaps)
+}
+
+func TestNewAuthenticatorOptions(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	secretFilePath := filepath.Join(pwd, "..", "..", "secrets/storage-secret-store/extra_key")
+	if err := k8s_utils.FakeCreateSecretWithKey(kc, utils.STORAGE_SECRET_STORE_SECRET, "extra-key", secretFilePath); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+
+	policy := RetryPolicy{MaxAttempts: 1}
+	authenticator, authType, err := NewAuthenticatorWithOptions(logger, kc, Options{
+		SecretKey:        "extra-key",
+		TokenExchangeURL: "https://iam.test.cloud.ibm.com",
+		RetryPolicy:      &policy,
+		LoggerFields:     []zap.Field{zap.String("component", "test")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, utils.DEFAULT, authType)
+	assert.Equal(t, "https://iam.test.cloud.ibm.com", authenticator.getURL())
+	assert.Equal(t, &policy, authenticator.(*APIKeyAuthenticator).retryPolicy)
+	assert.True(t, authenticator.(*APIKeyAuthenticator).userProvidedURL)
+
+	// Without a retry policy the default schedule is used
+	authenticator, _, err = NewAuthenticatorWithOptions(logger, kc, Options{SecretKey: "extra-key"})
+	assert.Nil(t, err)
+	assert.Nil(t, authenticator.(*APIKeyAuthenticator).retryPolicy)
+
+	// Unknown keys in the deprecated optional arguments are ignored
+	_, authType, err = NewAuthenticator(logger, kc, map[string]string{SecretKey: "extra-key", "Providertype": utils.Bluemix})
+	assert.Nil(t, err)
+	assert.Equal(t, utils.DEFAULT, authType)
+}
+
+func TestNewAuthenticatorMissingProviderSection(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	testcases := []struct {
+		testcasename string
+		fixture      string
+		providerType string
+		description  string
+	}{
+		{testcasename: "Missing VPC section", fixture: "missing_vpc_section.toml", providerType: utils.VPC, description: "[VPC]: section is missing"},
+		{testcasename: "Missing Bluemix section", fixture: "missing_bluemix_section.toml", providerType: utils.Bluemix, description: "[Bluemix]: section is missing"},
+		{testcasename: "Missing Softlayer section", fixture: "missing_softlayer_section.toml", providerType: utils.Softlayer, description: "[Softlayer]: section is missing"},
+		{testcasename: "Empty api key", fixture: "empty_apikey.toml", providerType: utils.VPC, description: "VPC.g2_api_key: is required"},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			kc, _ := k8s_utils.FakeGetk8sClientSet()
+			secretFilePath := filepath.Join(pwd, "..", "..", "test-fixtures/invalid", testcase.fixture)
+			if err := k8s_utils.FakeCreateSecret(kc, utils.DEFAULT, secretFilePath); err != nil {
+				t.Fatalf("Failed to create secret, error: %v", err)
+			}
+
+			_, _, err := NewAuthenticatorWithOptions(logger, kc, Options{ProviderType: testcase.providerType})
+			assert.NotNil(t, err)
+			assert.True(t, errors.Is(err, utils.ErrInvalidCredentials))
+			var validationErr config.ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			var utilsErr utils.Error
+			assert.True(t, errors.As(err, &utilsErr))
+			assert.Contains(t, utilsErr.Description, testcase.description)
+			assert.NotContains(t, utilsErr.Description, utils.ErrAPIKeyNotProvided)
+		})
+	}
+
+	// The section of another provider missing does not matter
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	secretFilePath := filepath.Join(pwd, "..", "..", "test-fixtures/invalid/missing_vpc_section.toml")
+	if err := k8s_utils.FakeCreateSecret(kc, utils.DEFAULT, secretFilePath); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+	_, authType, err := NewAuthenticatorWithOptions(logger, kc, Options{ProviderType: utils.Bluemix})
+	assert.Nil(t, err)
+	assert.Equal(t, utils.DEFAULT, authType)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	validSource := CredentialSource{
+		SecretSource: k8s_utils.SecretSource{SecretName: "secret", Key: "key"},
+		Format:       FormatAPIKey,
+	}
+
+	testcases := []struct {
+		testcasename string
+		opts         Options
+		expectError  bool
+	}{
+		{
+			testcasename: "Empty options",
+			opts:         Options{},
+		},
+		{
+			testcasename: "Valid options",
+			opts: Options{
+				ProviderType:      utils.Bluemix,
+				CredentialSources: []CredentialSource{validSource},
+				TokenExchangeURL:  "https://private.iam.cloud.ibm.com",
+				RetryPolicy:       &RetryPolicy{MaxAttempts: 1},
+			},
+		},
+		{
+			testcasename: "Invalid provider type",
+			opts:         Options{ProviderType: "vpc-gen2"},
+			expectError:  true,
+		},
+		{
+			testcasename: "Secret key and credential sources",
+			opts:         Options{SecretKey: "key", CredentialSources: []CredentialSource{validSource}},
+			expectError:  true,
+		},
+		{
+			testcasename: "Credential source without key",
+			opts:         Options{CredentialSources: []CredentialSource{{SecretSource: k8s_utils.SecretSource{SecretName: "secret"}, Format: FormatAPIKey}}},
+			expectError:  true,
+		},
+		{
+			testcasename: "Credential source with unknown format",
+			opts:         Options{CredentialSources: []CredentialSource{{SecretSource: validSource.SecretSource, Format: "toml"}}},
+			expectError:  true,
+		},
+		{
+			testcasename: "Relative token exchange URL",
+			opts:         Options{TokenExchangeURL: "iam.cloud.ibm.com"},
+			expectError:  true,
+		},
+		{
+			testcasename: "Retry policy without attempts",
+			opts:         Options{RetryPolicy: &RetryPolicy{}},
+			expectError:  true,
+		},
+		{
+			testcasename: "Token lifetime percent of 100",
+			opts:         Options{TokenLifetimePolicy: &token.LifetimePolicy{MinRemainingPercent: 100}},
+			expectError:  true,
+		},
+		{
+			testcasename: "Token lifetime percent above 100",
+			opts:         Options{TokenLifetimePolicy: &token.LifetimePolicy{MinRemainingPercent: 101}},
+			expectError:  true,
+		},
+		{
+			testcasename: "Negative minimum token lifetime",
+			opts:         Options{TokenLifetimePolicy: &token.LifetimePolicy{MinRemaining: -time.Minute}},
+			expectError:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			err := testcase.opts.Validate()
+			assert.Equal(t, testcase.expectError, err != nil)
+			if testcase.expectError {
+				assert.True(t, errors.Is(err, utils.ErrInvalidConfig))
+				assert.True(t, utils.RequiresUserAction(err))
+			}
+		})
+	}
+}
+
+func GetTestLogger(t *testing.T) (logger *zap.Logger, teardown func()) {
+	atom := zap.NewAtomicLevel()
+	atom.SetLevel(zap.DebugLevel)
+
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.TimeKey = "timestamp"
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	buf := &bytes.Buffer{}
+
+	logger = zap.New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(encoderCfg),
+			zapcore.AddSync(buf),
+			atom,
+		),
+		zap.AddCaller(),
+	)
+
+	teardown = func() {
+		_ = logger.Sync()
+		if t.Failed() {
+			t.Log(buf)
+		}
+	}
+	return
+}
+
+func TestNewAuthenticatorWithCredentialSources(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	tenantClient := k8s_utils.KubernetesClient{Namespace: "tenant-a", Clientset: kc.Clientset}
+	secretFilePath := filepath.Join(pwd, "..", "..", "secrets/ibm-cloud-credentials/iam-cloud-provider.env")
+	if err := k8s_utils.FakeCreateSecretWithKey(tenantClient, "tenant-credentials", "tenant.env", secretFilePath); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+	secretFilePath = filepath.Join(pwd, "..", "..", "secrets/storage-secret-store/slclient.toml")
+	if err := k8s_utils.FakeCreateSecret(kc, utils.DEFAULT, secretFilePath); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+
+	secretFilePath = filepath.Join(pwd, "..", "..", "test-fixtures/ibmcloud_credentials/invalid/emptyapikey.toml")
+	if err := k8s_utils.FakeCreateSecretWithKey(tenantClient, "invalid-credentials", "tenant.env", secretFilePath); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+
+	invalidSource := CredentialSource{
+		SecretSource: k8s_utils.SecretSource{Namespace: "tenant-a", SecretName: "invalid-credentials", Key: "tenant.env"},
+		Format:       FormatIBMCloudCredentials,
+	}
+	tenantSource := CredentialSource{
+		SecretSource: k8s_utils.SecretSource{Namespace: "tenant-a", SecretName: "tenant-credentials", Key: "tenant.env"},
+		Format:       FormatIBMCloudCredentials,
+	}
+	missingSource := CredentialSource{
+		SecretSource: k8s_utils.SecretSource{Namespace: "tenant-b", SecretName: "tenant-credentials", Key: "tenant.env"},
+		Format:       FormatIBMCloudCredentials,
+	}
+
+	testcases := []struct {
+		testcasename     string
+		opts             Options
+		expectedAuthType string
+		expectedSecret   string
+		expectError      bool
+		expectedCode     utils.ErrorCode
+		expectedMessage  string
+	}{
+		{
+			testcasename:     "Default sources",
+			opts:             Options{ProviderType: utils.Softlayer},
+			expectedAuthType: utils.DEFAULT,
+			expectedSecret:   "softlayer-api-key",
+		},
+		{
+			testcasename:     "Source in another namespace",
+			opts:             Options{CredentialSources: []CredentialSource{tenantSource}},
+			expectedAuthType: utils.IAM,
+			expectedSecret:   "api-key",
+		},
+		{
+			testcasename:     "Missing source followed by default sources",
+			opts:             Options{CredentialSources: append([]CredentialSource{missingSource}, DefaultCredentialSources()...)},
+			expectedAuthType: utils.DEFAULT,
+			expectedSecret:   "vpc-api-key",
+		},
+		{
+			testcasename:    "None of the sources exist",
+			opts:            Options{CredentialSources: []CredentialSource{missingSource}},
+			expectError:     true,
+			expectedCode:    utils.CredentialNotFound,
+			expectedMessage: "Unable to fetch data from secret",
+		},
+		{
+			testcasename:    "Missing source followed by a source with empty api key",
+			opts:            Options{CredentialSources: []CredentialSource{missingSource, invalidSource}},
+			expectError:     true,
+			expectedCode:    utils.InvalidCredentials,
+			expectedMessage: utils.ErrAPIKeyNotProvided,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			authenticator, authType, err := NewAuthenticatorWithOptions(logger, kc, testcase.opts)
+			if testcase.expectError {
+				var sourcesErr CredentialSourcesError
+				assert.True(t, errors.As(err, &sourcesErr))
+				assert.Equal(t, len(testcase.opts.CredentialSources), len(sourcesErr.Attempts))
+				assert.Equal(t, testcase.expectedCode, utils.GetErrorCode(err))
+				assert.True(t, errors.Is(err, utils.Error{Code: testcase.expectedCode}))
+				var libErr utils.Error
+				assert.True(t, errors.As(err, &libErr))
+				assert.Contains(t, libErr.Description, testcase.expectedMessage)
+				assert.Contains(t, err.Error(), testcase.expectedMessage)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedAuthType, authType)
+			assert.Equal(t, testcase.expectedSecret, authenticator.GetSecret())
+		})
+	}
+}
+
+func TestNewAuthenticatorLogsSecretStoreSource(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	tenantClient := k8s_utils.KubernetesClient{Namespace: "tenant-a", Clientset: kc.Clientset}
+	secretFilePath := filepath.Join(pwd, "..", "..", "secrets/storage-secret-store/slclient.toml")
+	if err := k8s_utils.FakeCreateSecretWithKey(tenantClient, "tenant-secret-store", utils.SECRET_STORE_FILE, secretFilePath); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+
+	core, logs := observer.New(zap.InfoLevel)
+	opts := Options{CredentialSources: []CredentialSource{{
+		SecretSource: k8s_utils.SecretSource{Namespace: "tenant-a", SecretName: "tenant-secret-store", Key: utils.SECRET_STORE_FILE},
+		Format:       FormatSecretStore,
+	}}}
+	_, _, err = NewAuthenticatorWithOptions(zap.New(core), kc, opts)
+	assert.Nil(t, err)
+
+	entries := logs.FilterMessage("Successfully initialized authenticator").All()
+	assert.Equal(t, 1, len(entries))
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "tenant-a", fields["namespace"])
+	assert.Equal(t, "tenant-secret-store", fields["secret-name"])
+	assert.Equal(t, utils.SECRET_STORE_FILE, fields["key-name"])
+}
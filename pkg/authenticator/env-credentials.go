@@ -0,0 +1,76 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// initAuthenticatorFromEnv initializes the authenticator when the library is used outside the cluster.
+// If IBMCLOUD_AUTHTYPE is set, the credentials are read from IBMCLOUD_AUTHTYPE, IBMCLOUD_APIKEY and IBMCLOUD_PROFILEID,
+// else the slclient.toml file pointed by SECRET_CONFIG_PATH is used.
+func initAuthenticatorFromEnv(logger *zap.Logger, providerName string) (Authenticator, string, error) {
+	credentialsmap := getIBMCloudCredentialsFromEnv()
+	if len(credentialsmap) != 0 {
+		credentialsmap, err := validateIBMCloudCredentials(logger, credentialsmap)
+		if err != nil {
+			logger.Error("Error validating credentials read from environment", zap.Error(err))
+			return nil, "", err
+		}
+		return initAuthenticatorFromCredentials(logger, credentialsmap, utils.ENV_CREDENTIALS)
+	}
+
+	logger.Info("IBMCLOUD_AUTHTYPE is not set, reading storage secret store config", zap.String("env", utils.SECRET_CONFIG_PATH))
+	secretConfigPath := strings.TrimSpace(os.Getenv(utils.SECRET_CONFIG_PATH))
+	if secretConfigPath == "" {
+		logger.Error("Credentials not found in environment")
+		return nil, "", utils.Error{Description: utils.ErrCredentialsUndefined, BackendError: utils.ErrSecretConfigPathUndefined, Code: utils.CredentialNotFound}
+	}
+
+	byteData, err := ioutil.ReadFile(secretConfigPath)
+	if err != nil {
+		logger.Error("Error reading storage secret store config", zap.String("path", secretConfigPath), zap.Error(err))
+		return nil, "", utils.Error{Description: fmt.Sprintf(utils.ErrReadingSecretConfig, secretConfigPath), BackendError: err.Error(), Code: utils.CredentialNotFound, Err: err}
+	}
+
+	return initAuthenticatorForStorageSecretStore(logger.With(zap.String("path", secretConfigPath)), providerName, string(byteData))
+}
+
+// getIBMCloudCredentialsFromEnv builds the same credentials map as parseIBMCloudCredentials,
+// using the environment variables instead of ibm-credentials.env.
+// An empty map is returned if IBMCLOUD_AUTHTYPE is not set.
+func getIBMCloudCredentialsFromEnv() utils.Credentials {
+	credentialsmap := make(utils.Credentials)
+	authType := strings.TrimSpace(os.Getenv(utils.IBMCLOUD_AUTHTYPE))
+	if authType == "" {
+		return credentialsmap
+	}
+
+	credentialsmap[utils.IBMCLOUD_AUTHTYPE] = authType
+	for _, key := range []string{utils.IBMCLOUD_APIKEY, utils.IBMCLOUD_PROFILEID} {
+		if value, ok := os.LookupEnv(key); ok {
+			credentialsmap[key] = strings.TrimSpace(value)
+		}
+	}
+	return credentialsmap
+}
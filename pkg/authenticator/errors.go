@@ -0,0 +1,88 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+)
+
+// SourceAttempt is a credential source which was tried, and the reason it could not be used.
+type SourceAttempt struct {
+	Source CredentialSource
+	Err    error
+}
+
+// CredentialSourcesError is returned when the authenticator could not be initialized using the credential sources,
+// it lists every source tried in order.
+type CredentialSourcesError struct {
+	Attempts []SourceAttempt
+}
+
+// Error ...
+func (err CredentialSourcesError) Error() string {
+	return err.asError().Error()
+}
+
+// As lets errors.As (and so utils.GetErrorCode) read the error as a utils.Error, see asError.
+func (err CredentialSourcesError) As(target interface{}) bool {
+	t, ok := target.(*utils.Error)
+	if !ok {
+		return false
+	}
+	*t = err.asError()
+	return true
+}
+
+// asError describes the error as a utils.Error, using the description and code of the last attempt which decides
+// the outcome (the source could not be read, or it was read but held empty or invalid data).
+// The failure of every attempt is listed in BackendError.
+func (err CredentialSourcesError) asError() utils.Error {
+	attempts := make([]string, 0, len(err.Attempts))
+	for _, attempt := range err.Attempts {
+		attempts = append(attempts, fmt.Sprintf("%s/%s[%s]: %v", attempt.Source.Namespace, attempt.Source.SecretName, attempt.Source.Key, attempt.Err))
+	}
+	sourcesErr := utils.Error{Description: utils.ErrCredentialSourcesNotFound, BackendError: strings.Join(attempts, "; "), Code: utils.CredentialNotFound}
+	if len(err.Attempts) == 0 {
+		return sourcesErr
+	}
+
+	last := err.Attempts[len(err.Attempts)-1].Err
+	sourcesErr.Err = last
+	sourcesErr.Code = utils.GetErrorCode(last)
+	var lastErr utils.Error
+	if errors.As(last, &lastErr) && lastErr.Description != "" {
+		sourcesErr.Description = lastErr.Description
+	} else {
+		sourcesErr.Description = last.Error()
+	}
+	return sourcesErr
+}
+
+// Unwrap returns the errors of all the sources tried, so that errors.Is and errors.As can reach them.
+// The last attempt decides the outcome (for example invalid data in a source found after missing sources),
+// hence it is returned first.
+func (err CredentialSourcesError) Unwrap() []error {
+	errs := make([]error, 0, len(err.Attempts))
+	for i := len(err.Attempts) - 1; i >= 0; i-- {
+		errs = append(errs, err.Attempts[i].Err)
+	}
+	return errs
+}
@@ -17,8 +17,13 @@
 package authenticator
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/IBM/secret-utils-lib/pkg/audit"
+	"github.com/IBM/secret-utils-lib/pkg/metrics"
+	"github.com/IBM/secret-utils-lib/pkg/token"
 	"go.uber.org/zap"
 )
 
@@ -34,13 +39,18 @@ func NewFakeAuthenticator(logger *zap.Logger) *FakeAuthenticator {
 }
 
 // GetToken ...
-func (fa *FakeAuthenticator) GetToken(freshTokenRequired bool) (string, uint64, error) {
+func (fa *FakeAuthenticator) GetToken(freshTokenRequired bool, reasonForCall ...string) (string, uint64, error) {
 	if !freshTokenRequired {
 		return "token", 1000, nil
 	}
 	return "", 0, errors.New("Not nil")
 }
 
+// GetTokenWithContext ...
+func (fa *FakeAuthenticator) GetTokenWithContext(ctx context.Context, freshTokenRequired bool, reasonForCall ...string) (string, uint64, error) {
+	return fa.GetToken(freshTokenRequired, reasonForCall...)
+}
+
 // GetSecret ...
 func (fa *FakeAuthenticator) GetSecret() string {
 	return fa.secret
@@ -67,6 +77,40 @@ func (fa *FakeAuthenticator) SetEncryption(encrypted bool) {
 	fa.logger.Info("Unimplemented")
 }
 
+// SetRetryPolicy ...
+func (fa *FakeAuthenticator) SetRetryPolicy(policy RetryPolicy) {
+	fa.logger.Info("Unimplemented")
+}
+
+// SetNegativeCacheTTL ...
+func (fa *FakeAuthenticator) SetNegativeCacheTTL(ttl time.Duration) {
+	fa.logger.Info("Unimplemented")
+}
+
+// SetMetricsRecorder ...
+func (fa *FakeAuthenticator) SetMetricsRecorder(recorder metrics.Recorder, provider string) {
+	fa.logger.Info("Unimplemented")
+}
+
+// SetAuditSink ...
+func (fa *FakeAuthenticator) SetAuditSink(sink audit.Sink, caller string) {
+	fa.logger.Info("Unimplemented")
+}
+
+// GetTokenClaims ...
+func (fa *FakeAuthenticator) GetTokenClaims() (*token.IAMClaims, error) {
+	return &token.IAMClaims{IAMID: "iam-ServiceId-fake", Account: token.IAMAccount{BSS: "fake-account", Valid: true}}, nil
+}
+
+// SetTokenLifetimePolicy ...
+func (fa *FakeAuthenticator) SetTokenLifetimePolicy(policy token.LifetimePolicy) {
+	fa.logger.Info("Unimplemented")
+}
+
 func (fa *FakeAuthenticator) getURL() string {
 	return fa.url
 }
+
+func (fa *FakeAuthenticator) setAuthType(authType string) {
+	fa.logger.Info("Unimplemented")
+}
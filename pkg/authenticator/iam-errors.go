@@ -0,0 +1,110 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+)
+
+// iamErrorResponse is the error returned by IAM in the body of a failed token request.
+type iamErrorResponse struct {
+	StatusCode   int
+	ErrorCode    string
+	ErrorMessage string
+}
+
+// knownIAMError maps an IAM error message to the description and the action to be taken by the user.
+type knownIAMError struct {
+	message     string
+	description string
+	action      string
+}
+
+// knownIAMErrors are matched (case insensitive) against the errorMessage returned by IAM.
+var knownIAMErrors = []knownIAMError{
+	{
+		message:     utils.APIKeyNotFound,
+		description: utils.APIKeyNotFound,
+		action:      "Rotate the API key in ibm-cloud-credentials (or storage-secret-store) with a valid API key",
+	},
+	{
+		message:     utils.UserNotFound,
+		description: utils.UserNotFound,
+		action:      "The owner of the API key is not an active user of the account, create an API key for an active user and update ibm-cloud-credentials (or storage-secret-store)",
+	},
+	{
+		message:     utils.ProfileNotFound,
+		description: utils.ProfileNotFound,
+		action:      "Add the cluster as a compute resource trusted by the profile, or update IBMCLOUD_PROFILEID in ibm-cloud-credentials",
+	},
+}
+
+// newIAMError classifies the error returned while fetching the token, errors other than timeouts
+// (including 408 Request Timeout), throttling and server errors mean that IAM rejected the credentials.
+// If the IAM error response matches a known case, the description and action are populated accordingly.
+func newIAMError(description string, err error) utils.Error {
+	iamErr := utils.Error{Description: description, BackendError: err.Error(), Code: utils.IAMUnavailable, Err: err}
+	if isTimeout(err) {
+		return iamErr
+	}
+
+	resp, ok := parseIAMErrorResponse(err)
+	if !ok {
+		return iamErr
+	}
+
+	if resp.StatusCode < http.StatusBadRequest || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout {
+		return iamErr
+	}
+
+	iamErr.Code = utils.IAMRejected
+	if resp.ErrorMessage != "" {
+		iamErr.BackendError = fmt.Sprintf("IAM error, status code: %d, error code: %s, error message: %s", resp.StatusCode, resp.ErrorCode, resp.ErrorMessage)
+	}
+	for _, known := range knownIAMErrors {
+		if strings.Contains(strings.ToLower(resp.ErrorMessage), known.message) {
+			iamErr.Description = known.description
+			iamErr.Action = known.action
+			break
+		}
+	}
+	return iamErr
+}
+
+// parseIAMErrorResponse extracts the status code, errorCode and errorMessage from the error returned by go-sdk-core,
+// false is returned if the error does not carry an IAM response.
+func parseIAMErrorResponse(err error) (iamErrorResponse, bool) {
+	var resp iamErrorResponse
+	var authErr *core.AuthenticationError
+	if !errors.As(err, &authErr) || authErr.HTTPProblem == nil || authErr.Response == nil {
+		return resp, false
+	}
+
+	resp.StatusCode = authErr.Response.StatusCode
+	if result, ok := authErr.Response.Result.(map[string]interface{}); ok {
+		resp.ErrorCode, _ = result["errorCode"].(string)
+		resp.ErrorMessage, _ = result["errorMessage"].(string)
+	}
+	return resp, true
+}
@@ -0,0 +1,298 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTokenIAMErrors(t *testing.T) {
+	testcases := []struct {
+		testcasename        string
+		podIdentity         bool
+		statusCode          int
+		body                string
+		expectedCode        utils.ErrorCode
+		expectedDescription string
+		expectAction        bool
+	}{
+		{
+			testcasename:        "API key not found",
+			statusCode:          http.StatusBadRequest,
+			body:                `{"errorCode":"BXNIM0415E","errorMessage":"Provided API key could not be found."}`,
+			expectedCode:        utils.IAMRejected,
+			expectedDescription: utils.APIKeyNotFound,
+			expectAction:        true,
+		},
+		{
+			testcasename:        "User not found",
+			statusCode:          http.StatusBadRequest,
+			body:                `{"errorCode":"BXNIM0513E","errorMessage":"User not found or active."}`,
+			expectedCode:        utils.IAMRejected,
+			expectedDescription: utils.UserNotFound,
+			expectAction:        true,
+		},
+		{
+			testcasename:        "Trusted profile not eligible",
+			podIdentity:         true,
+			statusCode:          http.StatusBadRequest,
+			body:                `{"errorCode":"BXNIM0538E","errorMessage":"Selected trusted profile not eligible for CR token."}`,
+			expectedCode:        utils.IAMRejected,
+			expectedDescription: utils.ProfileNotFound,
+			expectAction:        true,
+		},
+		{
+			testcasename:        "Unknown rejection",
+			statusCode:          http.StatusUnauthorized,
+			body:                `{"errorCode":"BXNIM0100E","errorMessage":"Unauthorized."}`,
+			expectedCode:        utils.IAMRejected,
+			expectedDescription: "Error fetching iam token using api key",
+		},
+		{
+			testcasename:        "Throttled",
+			statusCode:          http.StatusTooManyRequests,
+			body:                `{"errorCode":"BXNIM0429E","errorMessage":"Too many requests."}`,
+			expectedCode:        utils.IAMUnavailable,
+			expectedDescription: "Error fetching iam token using api key",
+		},
+		{
+			testcasename:        "Request timeout",
+			statusCode:          http.StatusRequestTimeout,
+			body:                `{"errorCode":"BXNIM0408E","errorMessage":"Request timeout."}`,
+			expectedCode:        utils.IAMUnavailable,
+			expectedDescription: "Error fetching iam token using api key",
+		},
+		{
+			testcasename:        "Server error",
+			podIdentity:         true,
+			statusCode:          http.StatusInternalServerError,
+			body:                `{"errorCode":"BXNIM0500E","errorMessage":"Internal error."}`,
+			expectedCode:        utils.IAMUnavailable,
+			expectedDescription: "Error fetching iam token using trusted profile",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			server := newStubIAM(t, testcase.statusCode, testcase.body)
+			defer server.Close()
+
+			auth := newTestAuthenticator(t, testcase.podIdentity, server.URL)
+			_, _, err := auth.GetToken(true)
+
+			var iamErr utils.Error
+			assert.True(t, errors.As(err, &iamErr))
+			assert.Equal(t, testcase.expectedCode, iamErr.Code)
+			assert.Equal(t, testcase.expectedDescription, iamErr.Description)
+			assert.Equal(t, testcase.expectAction, iamErr.Action != "")
+		})
+	}
+}
+
+func TestGetTokenFromStubIAM(t *testing.T) {
+	server := newStubIAM(t, http.StatusOK, fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":3600,"expiration":%d}`,
+		newTestToken(t, time.Hour), time.Now().Add(time.Hour).Unix()))
+	defer server.Close()
+
+	for _, podIdentity := range []bool{false, true} {
+		auth := newTestAuthenticator(t, podIdentity, server.URL)
+		token, lifetime, err := auth.GetToken(false)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, token)
+		assert.True(t, lifetime > 0)
+	}
+}
+
+func TestGetTokenNegativeCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errorCode":"BXNIM0415E","errorMessage":"Provided API key could not be found."}`))
+	}))
+	defer server.Close()
+
+	auth := newTestAuthenticator(t, false, server.URL)
+	_, _, err := auth.GetToken(true)
+	assert.True(t, errors.Is(err, utils.ErrIAMRejected))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// The same secret is not sent to IAM again, the cached failure is returned.
+	_, _, err = auth.GetToken(true)
+	var cachedErr utils.Error
+	assert.True(t, errors.As(err, &cachedErr))
+	assert.Equal(t, utils.ErrCachedAuthFailure, cachedErr.Description)
+	assert.NotEmpty(t, cachedErr.Action)
+	assert.True(t, errors.Is(err, utils.ErrIAMRejected))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// Updating the secret clears the cached failure.
+	auth.SetSecret("rotated-api-key")
+	_, _, err = auth.GetToken(true)
+	assert.True(t, errors.As(err, &cachedErr))
+	assert.Equal(t, utils.APIKeyNotFound, cachedErr.Description)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// The cached failure expires after the TTL.
+	auth.SetNegativeCacheTTL(10 * time.Millisecond)
+	_, _, _ = auth.GetToken(true)
+	time.Sleep(20 * time.Millisecond)
+	_, _, _ = auth.GetToken(true)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+
+	// A negative TTL disables the caching.
+	auth.SetNegativeCacheTTL(-1)
+	_, _, _ = auth.GetToken(true)
+	_, _, _ = auth.GetToken(true)
+	assert.Equal(t, int32(6), atomic.LoadInt32(&requests))
+}
+
+func TestGetTokenNegativeCacheFallback(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errorCode":"BXNIM0415E","errorMessage":"Provided API key could not be found."}`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	// Private IAM times out, public IAM (the stub) rejects the api key
+	var privateRequests, publicRequests int32
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasPrefix(utils.ProdPrivateIAMURL, "https://"+r.URL.Host) {
+			atomic.AddInt32(&privateRequests, 1)
+			return nil, errors.New("Client.Timeout exceeded while awaiting headers")
+		}
+		atomic.AddInt32(&publicRequests, 1)
+		r.URL.Scheme, r.URL.Host = serverURL.Scheme, serverURL.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	auth := NewIamAuthenticator("api-key", logger)
+	auth.authenticator.Client = &http.Client{Transport: transport}
+	auth.SetURL(utils.ProdPrivateIAMURL+"/identity/token", false)
+	auth.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	_, _, err := auth.GetToken(true)
+	assert.True(t, errors.Is(err, utils.ErrIAMRejected))
+	assert.Equal(t, utils.ProdPrivateIAMURL+"/identity/token", auth.getURL())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&privateRequests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&publicRequests))
+
+	// The rejection by public IAM is cached as well
+	_, _, err = auth.GetToken(true)
+	var cachedErr utils.Error
+	assert.True(t, errors.As(err, &cachedErr))
+	assert.Equal(t, utils.ErrCachedAuthFailure, cachedErr.Description)
+	assert.True(t, errors.Is(err, utils.ErrIAMRejected))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&privateRequests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&publicRequests))
+}
+
+// roundTripperFunc ...
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip ...
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestGetTokenNegativeCacheSkipsUnavailable(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"errorCode":"BXNIM0500E","errorMessage":"Internal error."}`))
+	}))
+	defer server.Close()
+
+	auth := newTestAuthenticator(t, true, server.URL)
+	_, _, _ = auth.GetToken(true)
+	_, _, err := auth.GetToken(true)
+	assert.True(t, errors.Is(err, utils.ErrIAMUnavailable))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+// newStubIAM returns a server which responds to every request with the status code and body given.
+func newStubIAM(t *testing.T, statusCode int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/identity/token" {
+			t.Errorf("Unexpected request path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+// newTestAuthenticator returns an api key or a trusted profile authenticator using the stub IAM, which makes a single attempt.
+func newTestAuthenticator(t *testing.T, podIdentity bool, url string) Authenticator {
+	logger, teardown := GetTestLogger(t)
+	t.Cleanup(teardown)
+
+	var auth Authenticator
+	if podIdentity {
+		crTokenPath := filepath.Join(t.TempDir(), "vault-token")
+		if err := ioutil.WriteFile(crTokenPath, []byte("cr-token"), 0600); err != nil {
+			t.Fatalf("Failed to write cr token, error: %v", err)
+		}
+		t.Setenv("IBMC_VAULT_TOKEN_PATH", crTokenPath)
+		auth = NewComputeIdentityAuthenticator("profile-id", logger)
+	} else {
+		auth = NewIamAuthenticator("api-key", logger)
+	}
+	auth.SetURL(url, true)
+	auth.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	return auth
+}
+
+// newTestToken returns a signed JWT with the given lifetime.
+func newTestToken(t *testing.T, lifetime time.Duration) string {
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iat":      now.Unix(),
+		"exp":      now.Add(lifetime).Unix(),
+		"iam_id":   "iam-ServiceId-1234",
+		"sub":      "ServiceId-1234",
+		"sub_type": "ServiceId",
+		"account":  map[string]interface{}{"bss": "account-1234", "valid": true},
+		"scope":    "ibm openid",
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token, error: %v", err)
+	}
+	return token
+}
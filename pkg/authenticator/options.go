@@ -0,0 +1,243 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/audit"
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/metrics"
+	"github.com/IBM/secret-utils-lib/pkg/token"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// FormatIBMCloudCredentials refers to data in the ibm-credentials.env format (IBMCLOUD_AUTHTYPE=...)
+	FormatIBMCloudCredentials = "ibm-credentials"
+
+	// FormatSecretStore refers to data in the slclient.toml format
+	FormatSecretStore = "secret-store"
+
+	// FormatAPIKey refers to data holding only the api key
+	FormatAPIKey = "api-key"
+)
+
+// CredentialSource is a key in a k8s secret from which the credentials are read.
+type CredentialSource struct {
+	k8s_utils.SecretSource
+	// Format of the data stored under the key, one of FormatIBMCloudCredentials, FormatSecretStore, FormatAPIKey.
+	Format string
+}
+
+// Options used to initialize the authenticator.
+type Options struct {
+	// CredentialSources are tried in order, the first source that can be read from the cluster is used.
+	// If empty, SecretKeyCredentialSources is used when SecretKey is provided, else DefaultCredentialSources.
+	CredentialSources []CredentialSource
+
+	// SecretKey is a key other than the default keys, to be read from ibm-cloud-credentials or storage-secret-store.
+	SecretKey string
+
+	// ProviderType is used to pick the api key from slclient.toml, one of vpc, bluemix, softlayer. Defaults to vpc.
+	ProviderType string
+
+	// TokenExchangeURL if provided, is used to fetch the token instead of the default IAM URL.
+	// The authenticator does not switch from private to public IAM on timeouts for a provided URL.
+	TokenExchangeURL string
+
+	// RetryPolicy used when fetching the token times out. If nil, the default schedule is kept: 9 attempts,
+	// waiting 2 seconds doubled up to 60 seconds after each timed out attempt, including the last one.
+	RetryPolicy *RetryPolicy
+
+	// TokenLifetimePolicy is the minimum remaining lifetime of the token in cache for GetToken to return it,
+	// else a fresh token is fetched. If nil, utils.TokenExpirydiff is used when set, else DefaultMinTokenLifetimePercent.
+	TokenLifetimePolicy *token.LifetimePolicy
+
+	// NegativeCacheTTL is how long a rejection of the secret by IAM (for example api key not found) is cached,
+	// GetToken fails fast with the cached error until it expires or the secret changes.
+	// Defaults to DefaultNegativeCacheTTL, a negative value disables the caching.
+	NegativeCacheTTL time.Duration
+
+	// Metrics records the token cache hits and misses, IAM latency, retries, fallbacks and token lifetime,
+	// labelled by auth type and provider. Metrics are not recorded if nil, see metrics.NewPrometheusRecorder.
+	Metrics metrics.Recorder
+
+	// AuditSink records every token returned by GetToken, with the caller and the reasonForCall.
+	// Tokens are not audited if nil, see audit.NewFileSink and audit.NewZapSink.
+	AuditSink audit.Sink
+
+	// AuditCaller is the name of the component using the authenticator, recorded in the audit events.
+	// Defaults to the name of the executable.
+	AuditCaller string
+
+	// LoggerFields are added to every log written by the authenticator.
+	LoggerFields []zap.Field
+}
+
+// RetryPolicy controls the retries made when fetching the token from IAM times out.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made to fetch the token.
+	MaxAttempts int
+
+	// InitialGap is the wait before the first retry, it is doubled after every retry.
+	InitialGap time.Duration
+
+	// MaxGap is the maximum wait between two attempts.
+	MaxGap time.Duration
+}
+
+// Validate checks the options before any secret is read, and returns all the problems found.
+func (opts Options) Validate() error {
+	var problems []string
+	if opts.ProviderType != "" && !IsProviderType(opts.ProviderType) {
+		problems = append(problems, utils.ErrInvalidProviderType)
+	}
+
+	if opts.SecretKey != "" && len(opts.CredentialSources) != 0 {
+		problems = append(problems, "SecretKey and CredentialSources cannot be provided together")
+	}
+
+	for i, source := range opts.CredentialSources {
+		if source.SecretName == "" || source.Key == "" {
+			problems = append(problems, fmt.Sprintf("secret name and key are required for credential source %d", i))
+		}
+		if source.Format != FormatIBMCloudCredentials && source.Format != FormatSecretStore && source.Format != FormatAPIKey {
+			problems = append(problems, fmt.Sprintf(utils.ErrUnknownCredentialFormat, source.Format))
+		}
+	}
+
+	if opts.TokenExchangeURL != "" {
+		if u, err := url.Parse(opts.TokenExchangeURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("token exchange URL %s is not an absolute URL", opts.TokenExchangeURL))
+		}
+	}
+
+	if policy := opts.RetryPolicy; policy != nil {
+		if policy.MaxAttempts < 1 {
+			problems = append(problems, "retry policy must allow at least one attempt")
+		}
+		if policy.InitialGap < 0 || policy.MaxGap < 0 {
+			problems = append(problems, "retry policy gaps cannot be negative")
+		}
+	}
+
+	if policy := opts.TokenLifetimePolicy; policy != nil {
+		if policy.MinRemaining < 0 {
+			problems = append(problems, "minimum remaining token lifetime cannot be negative")
+		}
+		// With 100 percent, a token is never usable once a second has passed since it was issued
+		if policy.MinRemainingPercent < 0 || policy.MinRemainingPercent >= 100 {
+			problems = append(problems, "minimum remaining token lifetime percent must be between 0 and 99")
+		}
+	}
+
+	if len(problems) != 0 {
+		return utils.Error{Description: utils.ErrInvalidOptions, BackendError: strings.Join(problems, "; "), Code: utils.InvalidConfig}
+	}
+	return nil
+}
+
+// DefaultRetryPolicy returns the attempts and gaps of the default schedule as a retry policy.
+// Unlike the default schedule, a policy does not wait after the last attempt and returns its timeout.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: maxRetryAttempt, InitialGap: initialRetryGap, MaxGap: maxRetryGap}
+}
+
+// DefaultCredentialSources returns the sources used when none are provided,
+// ibm-credentials.env in ibm-cloud-credentials, followed by slclient.toml in storage-secret-store,
+// both in the namespace of the k8s client.
+func DefaultCredentialSources() []CredentialSource {
+	return []CredentialSource{
+		{
+			SecretSource: k8s_utils.SecretSource{SecretName: utils.IBMCLOUD_CREDENTIALS_SECRET, Key: utils.CLOUD_PROVIDER_ENV},
+			Format:       FormatIBMCloudCredentials,
+		},
+		{
+			SecretSource: k8s_utils.SecretSource{SecretName: utils.STORAGE_SECRET_STORE_SECRET, Key: utils.SECRET_STORE_FILE},
+			Format:       FormatSecretStore,
+		},
+	}
+}
+
+// SecretKeyCredentialSources returns the sources used when a specific key is to be read,
+// the key in ibm-cloud-credentials (ibm-credentials.env format), followed by the key in storage-secret-store (api key).
+func SecretKeyCredentialSources(secretKey string) []CredentialSource {
+	return []CredentialSource{
+		{
+			SecretSource: k8s_utils.SecretSource{SecretName: utils.IBMCLOUD_CREDENTIALS_SECRET, Key: secretKey},
+			Format:       FormatIBMCloudCredentials,
+		},
+		{
+			SecretSource: k8s_utils.SecretSource{SecretName: utils.STORAGE_SECRET_STORE_SECRET, Key: secretKey},
+			Format:       FormatAPIKey,
+		},
+	}
+}
+
+// SecretStoreSources returns the slclient.toml sources among the credential sources,
+// these can be passed to config.FrameTokenExchangeURL so that both read the same secrets.
+func (opts Options) SecretStoreSources() []k8s_utils.SecretSource {
+	var sources []k8s_utils.SecretSource
+	for _, source := range opts.credentialSources() {
+		if source.Format == FormatSecretStore {
+			sources = append(sources, source.SecretSource)
+		}
+	}
+	return sources
+}
+
+// credentialSources ...
+func (opts Options) credentialSources() []CredentialSource {
+	if len(opts.CredentialSources) != 0 {
+		return opts.CredentialSources
+	}
+	if opts.SecretKey != "" {
+		return SecretKeyCredentialSources(opts.SecretKey)
+	}
+	return DefaultCredentialSources()
+}
+
+// auditCaller ...
+func (opts Options) auditCaller() string {
+	if opts.AuditCaller == "" {
+		return filepath.Base(os.Args[0])
+	}
+	return opts.AuditCaller
+}
+
+// negativeCacheTTL ...
+func (opts Options) negativeCacheTTL() time.Duration {
+	if opts.NegativeCacheTTL == 0 {
+		return DefaultNegativeCacheTTL
+	}
+	return opts.NegativeCacheTTL
+}
+
+// providerType ...
+func (opts Options) providerType() string {
+	if opts.ProviderType == "" {
+		return utils.VPC
+	}
+	return opts.ProviderType
+}
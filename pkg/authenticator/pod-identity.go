@@ -17,20 +17,18 @@
 package authenticator
 
 import (
+	"context"
 	"os"
 
 	"github.com/IBM/go-sdk-core/v5/core"
-	"github.com/IBM/secret-utils-lib/pkg/token"
 	"github.com/IBM/secret-utils-lib/pkg/utils"
 	"go.uber.org/zap"
 )
 
 // ComputeIdentityAuthenticator ...
 type ComputeIdentityAuthenticator struct {
-	authenticator   *core.ContainerAuthenticator
-	logger          *zap.Logger
-	token           string
-	userProvidedURL bool
+	authenticator *core.ContainerAuthenticator
+	tokenManager
 }
 
 // NewComputeIdentityAuthenticator ...
@@ -41,67 +39,18 @@ func NewComputeIdentityAuthenticator(profileID string, logger *zap.Logger) *Comp
 	if vaultPath := os.Getenv("IBMC_VAULT_TOKEN_PATH"); vaultPath != "" {
 		ca.authenticator.CRTokenFilename = vaultPath
 	}
-	ca.logger = logger
+	ca.tokenManager = newTokenManager(logger, utils.PODIDENTITY)
 	return ca
 }
 
 // GetToken ...
-func (ca *ComputeIdentityAuthenticator) GetToken(freshTokenRequired bool) (string, uint64, error) {
-	var err error
-	var tokenlifetime uint64
-
-	if !freshTokenRequired {
-		// Fetching token life time of the token in cache
-		tokenlifetime, err = token.CheckTokenLifeTime(ca.token)
-		if err == nil {
-			ca.logger.Info("Fetched iam token from cache", zap.Uint64("token-life-time-in-seconds", tokenlifetime))
-			return ca.token, tokenlifetime, nil
-		}
-	}
-
-	var tokenResponse *core.IamTokenServerResponse
-	err = retry(ca.logger, func() error {
-		tokenResponse, err = ca.authenticator.RequestToken()
-		return err
-	})
-
-	if err != nil {
-		// If the error is not related to timeout or if the token exchange URL is provided by user, return error.
-		if !isTimeout(err) || ca.userProvidedURL {
-			return "", tokenlifetime, utils.Error{Description: "Error fetching iam token using trusted profile", BackendError: err.Error()}
-		}
-
-		// By default authenticator uses private IAM URL, setting it to public
-		setPublicIAMURL(ca)
-
-		// Retry fetching IAM token after switching from private to public IAM URL.
-		ca.logger.Info("Updated IAM URL from private to public, retrying to fetch IAM token")
-		err = retry(ca.logger, func() error {
-			tokenResponse, err = ca.authenticator.RequestToken()
-			return err
-		})
-
-		// Resetting to private IAM URL.
-		setPrivateIAMURL(ca)
-		if err != nil {
-			return "", tokenlifetime, utils.Error{Description: "Error fetching iam token using trusted profile", BackendError: err.Error()}
-		}
-	}
-
-	if tokenResponse == nil {
-		ca.logger.Error("Token response received is empty")
-		return "", tokenlifetime, utils.Error{Description: utils.ErrEmptyTokenResponse}
-	}
-
-	tokenlifetime, err = token.CheckTokenLifeTime(tokenResponse.AccessToken)
-	if err != nil {
-		ca.logger.Error("Error fetching token lifetime for new token", zap.Error(err))
-		return "", tokenlifetime, utils.Error{Description: "Error fetching token lifetime", BackendError: err.Error()}
-	}
-	ca.token = tokenResponse.AccessToken
+func (ca *ComputeIdentityAuthenticator) GetToken(freshTokenRequired bool, reasonForCall ...string) (string, uint64, error) {
+	return ca.GetTokenWithContext(context.Background(), freshTokenRequired, reasonForCall...)
+}
 
-	ca.logger.Info("Fetched fresh iam token", zap.Uint64("token-life-time-in-seconds", tokenlifetime))
-	return ca.token, tokenlifetime, nil
+// GetTokenWithContext is GetToken, recording the GetToken span as a child of the span in ctx.
+func (ca *ComputeIdentityAuthenticator) GetTokenWithContext(ctx context.Context, freshTokenRequired bool, reasonForCall ...string) (string, uint64, error) {
+	return ca.getToken(ctx, ca, freshTokenRequired, reasonForCall, ca.authenticator.RequestToken, "Error fetching iam token using trusted profile")
 }
 
 // GetSecret ...
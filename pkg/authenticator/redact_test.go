@@ -0,0 +1,232 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/audit"
+	"github.com/IBM/secret-utils-lib/pkg/config"
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	leakedAPIKey       = "leak-apikey-7f3a"
+	leakedProfileID    = "leak-profileid-91c2"
+	leakedVPCAPIKey    = "leakVPCapikey0b4d"
+	leakedBXAPIKey     = "leak-bluemix-apikey-6e1f"
+	leakedSLAPIKey     = "leak-softlayer-apikey-c8a5"
+	leakedClientSecret = "leak-client-secret-2d9e"
+	leakedPassthrough  = "leak-passthrough-5a70"
+)
+
+var leakedSlclient = fmt.Sprintf(`[Bluemix]
+  iam_client_secret = "%s"
+  iam_api_key = "%s"
+[Softlayer]
+  softlayer_api_key = "%s"
+[VPC]
+  iam_client_secret = "%s"
+  g2_api_key = "%s"
+[API]
+  PassthroughSecret = "%s"
+`, leakedClientSecret, leakedBXAPIKey, leakedSLAPIKey, leakedClientSecret, leakedVPCAPIKey, leakedPassthrough)
+
+// newCaptureLogger returns a logger writing every level to the buffer returned.
+func newCaptureLogger() (*zap.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(buf), zap.DebugLevel))
+	return logger, buf
+}
+
+// assertNoSecrets fails if any of the secrets, or a part of them long enough to identify it, is found in output.
+func assertNoSecrets(t *testing.T, output string, secrets ...string) {
+	for _, secret := range secrets {
+		for _, part := range []string{secret, secret[:len(secret)-4], secret[5:]} {
+			assert.False(t, strings.Contains(output, part), "secret %q (%q) found in output:\n%s", secret, part, output)
+		}
+	}
+}
+
+func TestNoSecretsInLogs(t *testing.T) {
+	iamToken := newTestToken(t, time.Hour)
+	okIAM := newStubIAM(t, http.StatusOK, fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":3600,"expiration":%d}`,
+		iamToken, time.Now().Add(time.Hour).Unix()))
+	defer okIAM.Close()
+	rejectingIAM := newStubIAM(t, http.StatusBadRequest, `{"errorCode":"BXNIM0415E","errorMessage":"Provided API key could not be found."}`)
+	defer rejectingIAM.Close()
+
+	crTokenPath := filepath.Join(t.TempDir(), "vault-token")
+	if err := ioutil.WriteFile(crTokenPath, []byte("cr-token"), 0600); err != nil {
+		t.Fatalf("Failed to write cr token, error: %v", err)
+	}
+	t.Setenv("IBMC_VAULT_TOKEN_PATH", crTokenPath)
+
+	secrets := []string{leakedAPIKey, leakedProfileID, leakedVPCAPIKey, leakedBXAPIKey, leakedSLAPIKey, leakedClientSecret, leakedPassthrough, iamToken}
+
+	testcases := []struct {
+		testcasename string
+		secretKey    string
+		data         string
+		opts         Options
+		env          map[string]string
+		// logsCredentials is set if the (redacted) credentials are expected in the logs
+		logsCredentials bool
+	}{
+		{
+			testcasename:    "API key in ibm-credentials.env",
+			secretKey:       utils.CLOUD_PROVIDER_ENV,
+			logsCredentials: true,
+			data:            fmt.Sprintf("IBMCLOUD_AUTHTYPE=iam\nIBMCLOUD_APIKEY=%s\n", leakedAPIKey),
+		},
+		{
+			testcasename:    "Trusted profile in ibm-credentials.env",
+			secretKey:       utils.CLOUD_PROVIDER_ENV,
+			logsCredentials: true,
+			data:            fmt.Sprintf("IBMCLOUD_AUTHTYPE=pod-identity\nIBMCLOUD_PROFILEID=%s\n", leakedProfileID),
+		},
+		{
+			testcasename: "Unknown auth type in ibm-credentials.env",
+			secretKey:    utils.CLOUD_PROVIDER_ENV,
+			data:         fmt.Sprintf("IBMCLOUD_AUTHTYPE=apikey\nIBMCLOUD_APIKEY=%s\n", leakedAPIKey),
+		},
+		{
+			testcasename:    "Empty profile ID in ibm-credentials.env",
+			secretKey:       utils.CLOUD_PROVIDER_ENV,
+			logsCredentials: true,
+			data:            fmt.Sprintf("IBMCLOUD_AUTHTYPE=pod-identity\nIBMCLOUD_APIKEY=%s\n", leakedAPIKey),
+		},
+		{
+			testcasename: "slclient.toml for vpc",
+			data:         leakedSlclient,
+			opts:         Options{ProviderType: utils.VPC},
+		},
+		{
+			testcasename: "slclient.toml for bluemix",
+			data:         leakedSlclient,
+			opts:         Options{ProviderType: utils.Bluemix},
+		},
+		{
+			testcasename: "slclient.toml for softlayer",
+			data:         leakedSlclient,
+			opts:         Options{ProviderType: utils.Softlayer},
+		},
+		{
+			testcasename: "Malformed slclient.toml with an unquoted api key",
+			data:         fmt.Sprintf("[VPC]\n  g2_api_key = %s\n", leakedVPCAPIKey),
+		},
+		{
+			testcasename: "API key stored under a custom key",
+			secretKey:    "custom-key",
+			data:         leakedAPIKey,
+			opts:         Options{CredentialSources: []CredentialSource{{SecretSource: k8s_utils.SecretSource{SecretName: "custom-secret", Key: "custom-key"}, Format: FormatAPIKey}}},
+		},
+		{
+			testcasename:    "API key in environment",
+			env:             map[string]string{utils.IBMCLOUD_AUTHTYPE: utils.IAM, utils.IBMCLOUD_APIKEY: leakedAPIKey},
+			logsCredentials: true,
+		},
+		{
+			testcasename:    "Trusted profile in environment",
+			env:             map[string]string{utils.IBMCLOUD_AUTHTYPE: utils.PODIDENTITY, utils.IBMCLOUD_PROFILEID: leakedProfileID},
+			logsCredentials: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			logger, buf := newCaptureLogger()
+
+			kc, _ := k8s_utils.FakeGetk8sClientSet()
+			if testcase.env != nil {
+				kc = k8s_utils.KubernetesClient{}
+				for key, value := range testcase.env {
+					t.Setenv(key, value)
+				}
+			} else {
+				secretName, secretKey := utils.STORAGE_SECRET_STORE_SECRET, utils.SECRET_STORE_FILE
+				if testcase.secretKey == utils.CLOUD_PROVIDER_ENV {
+					secretName, secretKey = utils.IBMCLOUD_CREDENTIALS_SECRET, testcase.secretKey
+				} else if testcase.secretKey != "" {
+					secretName, secretKey = "custom-secret", testcase.secretKey
+				}
+				dataPath := filepath.Join(t.TempDir(), "data")
+				if err := ioutil.WriteFile(dataPath, []byte(testcase.data), 0600); err != nil {
+					t.Fatalf("Failed to write secret data, error: %v", err)
+				}
+				if err := k8s_utils.FakeCreateSecretWithKey(kc, secretName, secretKey, dataPath); err != nil {
+					t.Fatalf("Failed to create secret, error: %v", err)
+				}
+			}
+
+			opts := testcase.opts
+			opts.RetryPolicy = &RetryPolicy{MaxAttempts: 1}
+			opts.AuditSink = audit.NewZapSink(logger)
+			auth, _, err := NewAuthenticatorWithOptions(logger, kc, opts)
+			if err != nil {
+				logger.Error("Error initializing authenticator", zap.Error(err), zap.String("error", fmt.Sprintf("%+v", err)))
+			} else {
+				// Rejected, then served from the negative cache, then a fresh and a cached token.
+				auth.SetURL(rejectingIAM.URL, true)
+				for i := 0; i < 2; i++ {
+					_, _, err = auth.GetToken(true, "test")
+					logger.Error("Error fetching token", zap.Error(err), zap.String("error", fmt.Sprintf("%+v", err)))
+				}
+				auth.SetURL(okIAM.URL, true)
+				auth.SetNegativeCacheTTL(-1)
+				_, _, err = auth.GetToken(true, "test")
+				assert.Nil(t, err)
+				_, _, err = auth.GetToken(false, "test")
+				assert.Nil(t, err)
+			}
+
+			assertNoSecrets(t, buf.String(), secrets...)
+			if testcase.logsCredentials {
+				assert.Contains(t, buf.String(), `"credentials":{`)
+				assert.Contains(t, buf.String(), utils.RedactedValue)
+			}
+		})
+	}
+}
+
+func TestRedactedConfigAndCredentials(t *testing.T) {
+	logger, buf := newCaptureLogger()
+
+	conf, err := config.ParseConfig(logger, leakedSlclient)
+	assert.Nil(t, err)
+	credentials := utils.Credentials{utils.IBMCLOUD_AUTHTYPE: utils.IAM, utils.IBMCLOUD_APIKEY: leakedAPIKey, utils.IBMCLOUD_PROFILEID: leakedProfileID}
+
+	logger.Info("config", zap.Object("config", conf), zap.Any("any", conf), zap.Any("vpc", conf.VPC), zap.Any("bluemix", *conf.Bluemix))
+	logger.Info("credentials", zap.Object("credentials", credentials), zap.Any("any", credentials), zap.Stringer("stringer", credentials))
+	logger.Info("formatted", zap.String("credentials", fmt.Sprintf("%v", credentials)))
+
+	assertNoSecrets(t, buf.String(), leakedAPIKey, leakedProfileID, leakedVPCAPIKey, leakedBXAPIKey, leakedSLAPIKey, leakedClientSecret, leakedPassthrough)
+	assert.Contains(t, buf.String(), utils.RedactedValue)
+	assert.Contains(t, buf.String(), `"IBMCLOUD_AUTHTYPE":"iam"`)
+	assert.Contains(t, buf.String(), `"g2_riaas_endpoint_url"`)
+}
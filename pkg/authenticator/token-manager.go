@@ -0,0 +1,291 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/secret-utils-lib/pkg/audit"
+	"github.com/IBM/secret-utils-lib/pkg/metrics"
+	"github.com/IBM/secret-utils-lib/pkg/token"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// tokenManager holds the token cache and the settings shared by the authenticators which fetch the token from IAM.
+type tokenManager struct {
+	logger           *zap.Logger
+	token            string
+	userProvidedURL  bool
+	retryPolicy      *RetryPolicy
+	negativeCacheTTL time.Duration
+	failure          *cachedFailure
+	recorder         metrics.Recorder
+	labels           metrics.Labels
+	auditSink        audit.Sink
+	auditCaller      string
+	lifetimePolicy   *token.LifetimePolicy
+}
+
+// cachedFailure is a permanent authentication failure, returned without calling IAM until it expires or the secret changes.
+type cachedFailure struct {
+	err    utils.Error
+	secret string
+	expiry time.Time
+}
+
+// newTokenManager ...
+func newTokenManager(logger *zap.Logger, authType string) tokenManager {
+	return tokenManager{
+		logger:           logger,
+		negativeCacheTTL: DefaultNegativeCacheTTL,
+		recorder:         metrics.NoopRecorder{},
+		labels:           metrics.Labels{AuthType: authType},
+		auditSink:        audit.NoopSink{},
+	}
+}
+
+// SetAuditSink sets the sink recording the tokens returned, caller is the name of the component using the authenticator.
+func (tm *tokenManager) SetAuditSink(sink audit.Sink, caller string) {
+	if sink == nil {
+		sink = audit.NoopSink{}
+	}
+	tm.auditSink = sink
+	tm.auditCaller = caller
+}
+
+// SetTokenLifetimePolicy sets the minimum remaining lifetime of the token in cache for it to be returned.
+func (tm *tokenManager) SetTokenLifetimePolicy(policy token.LifetimePolicy) {
+	tm.lifetimePolicy = &policy
+}
+
+// tokenLifetimePolicy returns the policy set for the authenticator, else utils.TokenExpirydiff if set
+// (read on every call, as importers may set it at any time), else DefaultMinTokenLifetimePercent.
+func (tm *tokenManager) tokenLifetimePolicy() token.LifetimePolicy {
+	if tm.lifetimePolicy != nil {
+		return *tm.lifetimePolicy
+	}
+	if utils.TokenExpirydiff != 0 {
+		return token.LifetimePolicy{MinRemaining: time.Duration(utils.TokenExpirydiff) * time.Second}
+	}
+	return token.LifetimePolicy{MinRemainingPercent: DefaultMinTokenLifetimePercent}
+}
+
+// GetTokenClaims returns the claims of the token in cache, without calling IAM.
+// The claims are returned even if the token has expired, error is returned if no token was fetched yet.
+func (tm *tokenManager) GetTokenClaims() (*token.IAMClaims, error) {
+	if tm.token == "" {
+		return nil, utils.Error{Description: utils.ErrNoCachedToken}
+	}
+
+	claims, err := token.ParseIAMClaimsUnverified(tm.token)
+	if err != nil {
+		tm.logger.Error("Error parsing claims of the token in cache", zap.Error(err))
+		return nil, utils.Error{Description: utils.ErrParsingTokenClaims, BackendError: err.Error(), Err: err}
+	}
+	return claims, nil
+}
+
+// audit records the token returned to the caller, a failure to record it is logged and does not fail GetToken.
+func (tm *tokenManager) audit(iamToken string, tokenlifetime uint64, reasonForCall []string, cacheHit bool) {
+	if _, ok := tm.auditSink.(audit.NoopSink); ok {
+		return
+	}
+
+	now := time.Now()
+	event := audit.Event{
+		Time:     now,
+		Caller:   tm.auditCaller,
+		Reason:   strings.Join(reasonForCall, ", "),
+		AuthType: tm.labels.AuthType,
+		Expiry:   now.Add(time.Duration(tokenlifetime) * time.Second),
+		CacheHit: cacheHit,
+	}
+
+	var err error
+	if event.Subject, event.IAMID, err = token.GetTokenSubject(iamToken); err != nil {
+		tm.logger.Warn("Unable to read the subject of the token for audit", zap.Error(err))
+	}
+	if err = tm.auditSink.Record(event); err != nil {
+		tm.logger.Error("Error recording audit event", zap.String("caller", event.Caller), zap.String("reason", event.Reason), zap.Error(err))
+	}
+}
+
+// SetMetricsRecorder sets the recorder of the token metrics, labelled with the provider given.
+func (tm *tokenManager) SetMetricsRecorder(recorder metrics.Recorder, provider string) {
+	if recorder == nil {
+		recorder = metrics.NoopRecorder{}
+	}
+	tm.recorder = recorder
+	tm.labels.Provider = provider
+}
+
+// setAuthType sets the auth type labelling the metrics, spans and audit events, it is the auth type returned by NewAuthenticator
+// (for example utils.DEFAULT if the api key was read from storage-secret-store).
+func (tm *tokenManager) setAuthType(authType string) {
+	tm.labels.AuthType = authType
+}
+
+// instrumentRequest wraps requestToken, recording the latency of the request and the retries,
+// and a RequestToken span per attempt in ctx. fallback is true if the request is made to public IAM after private IAM timed out.
+func (tm *tokenManager) instrumentRequest(ctx context.Context, auth Authenticator, requestToken func() (*core.IamTokenServerResponse, error), fallback bool) func() (*core.IamTokenServerResponse, error) {
+	attempt := 0
+	return func() (*core.IamTokenServerResponse, error) {
+		attempt++
+		if attempt > 1 {
+			tm.recorder.Retry(tm.labels)
+		}
+		_, span := utils.StartSpan(ctx, "RequestToken", utils.AttrAuthType.String(tm.labels.AuthType), utils.AttrEndpoint.String(auth.getURL()),
+			utils.AttrAttempt.Int(attempt), utils.AttrFallback.Bool(fallback))
+		start := time.Now()
+		tokenResponse, err := requestToken()
+		tm.recorder.ObserveIAMRequest(tm.labels, auth.getURL(), time.Since(start), err)
+		utils.EndSpan(span, err)
+		return tokenResponse, err
+	}
+}
+
+// SetRetryPolicy ...
+func (tm *tokenManager) SetRetryPolicy(policy RetryPolicy) {
+	tm.retryPolicy = &policy
+}
+
+// SetNegativeCacheTTL sets how long a rejection of the secret by IAM is cached, zero or negative disables the caching.
+func (tm *tokenManager) SetNegativeCacheTTL(ttl time.Duration) {
+	tm.negativeCacheTTL = ttl
+	tm.failure = nil
+}
+
+// cachedFailure returns the cached authentication failure if it is still valid for secret.
+func (tm *tokenManager) cachedFailure(secret string) (utils.Error, bool) {
+	if tm.failure == nil {
+		return utils.Error{}, false
+	}
+	if tm.failure.secret != secret || time.Now().After(tm.failure.expiry) {
+		tm.logger.Info("Clearing cached authentication failure, secret changed or cache expired")
+		tm.failure = nil
+		return utils.Error{}, false
+	}
+	return tm.failure.err, true
+}
+
+// cacheFailure caches err if IAM rejected the secret, so that the next calls do not reach IAM with the same secret.
+func (tm *tokenManager) cacheFailure(secret string, err utils.Error) {
+	if err.Code != utils.IAMRejected || tm.negativeCacheTTL <= 0 {
+		return
+	}
+	tm.logger.Warn("IAM rejected the secret, caching the failure", zap.Duration("ttl", tm.negativeCacheTTL))
+	tm.failure = &cachedFailure{err: err, secret: secret, expiry: time.Now().Add(tm.negativeCacheTTL)}
+}
+
+// getToken returns the token in cache if it is valid and freshTokenRequired is false,
+// else fetches a fresh token from IAM using requestToken.
+// auth is the authenticator owning the token manager, used to switch between private and public IAM URL.
+// reasonForCall is recorded in the audit events, the GetToken span is a child of the span in ctx.
+func (tm *tokenManager) getToken(ctx context.Context, auth Authenticator, freshTokenRequired bool, reasonForCall []string, requestToken func() (*core.IamTokenServerResponse, error), errDescription string) (iamToken string, tokenlifetime uint64, err error) {
+	ctx, span := utils.StartSpan(ctx, "GetToken", utils.AttrAuthType.String(tm.labels.AuthType),
+		utils.AttrProvider.String(tm.labels.Provider), utils.AttrFreshTokenRequired.Bool(freshTokenRequired))
+	cacheHit := false
+	defer func() {
+		span.SetAttributes(utils.AttrCacheHit.Bool(cacheHit))
+		utils.EndSpan(span, err)
+	}()
+
+	if !freshTokenRequired {
+		// Fetching token life time of the token in cache
+		tokenlifetime, err = token.CheckTokenLifeTimeWithPolicy(tm.token, tm.tokenLifetimePolicy())
+		if err == nil {
+			tm.logger.Info("Fetched iam token from cache", zap.Uint64("token-life-time-in-seconds", tokenlifetime))
+			tm.recorder.CacheHit(tm.labels)
+			cacheHit = true
+			tm.audit(tm.token, tokenlifetime, reasonForCall, true)
+			tm.recorder.SetTokenLifetime(tm.labels, time.Duration(tokenlifetime)*time.Second)
+			return tm.token, tokenlifetime, nil
+		}
+		// A forced refresh is not a cache miss
+		tm.recorder.CacheMiss(tm.labels)
+	}
+
+	// If IAM rejected the same secret recently, fail fast instead of calling IAM again.
+	secret := auth.GetSecret()
+	if failure, ok := tm.cachedFailure(secret); ok {
+		tm.logger.Error("IAM rejected the secret recently, returning the cached failure", zap.Error(failure))
+		return "", tokenlifetime, utils.Error{Description: utils.ErrCachedAuthFailure, BackendError: failure.Error(), Action: failure.Action, Code: failure.Code, Err: failure}
+	}
+
+	var tokenResponse *core.IamTokenServerResponse
+	request := tm.instrumentRequest(ctx, auth, requestToken, false)
+	err = retry(tm.logger, tm.retryPolicy, func() error {
+		tokenResponse, err = request()
+		return err
+	})
+
+	if err != nil {
+		// If the error is not related to timeout or if the token exchange URL is provided by user, return error.
+		if !isTimeout(err) || tm.userProvidedURL {
+			iamErr := newIAMError(errDescription, err)
+			tm.cacheFailure(secret, iamErr)
+			return "", tokenlifetime, iamErr
+		}
+
+		// By default authenticator uses private IAM URL, setting it to public
+		setPublicIAMURL(auth)
+
+		// Retry fetching IAM token after switching from private to public IAM URL.
+		tm.logger.Info("Updated IAM URL from private to public, retrying to fetch IAM token")
+		tm.recorder.Fallback(tm.labels)
+		request = tm.instrumentRequest(ctx, auth, requestToken, true)
+		err = retry(tm.logger, tm.retryPolicy, func() error {
+			tokenResponse, err = request()
+			return err
+		})
+
+		// Resetting to private IAM URL.
+		setPrivateIAMURL(auth)
+		if err != nil {
+			iamErr := newIAMError(errDescription, err)
+			tm.cacheFailure(secret, iamErr)
+			return "", tokenlifetime, iamErr
+		}
+	}
+
+	if tokenResponse == nil {
+		tm.logger.Error("Token response received is empty")
+		return "", tokenlifetime, utils.Error{Description: utils.ErrEmptyTokenResponse, Code: utils.IAMUnavailable}
+	}
+
+	// The lifetime policy decides when the token in cache is refreshed, a token just fetched is returned even if
+	// it does not meet the policy (for example if the policy asks for more than the lifetime of IAM tokens).
+	tokenlifetime, err = token.CheckTokenLifeTimeWithPolicy(tokenResponse.AccessToken, token.LifetimePolicy{})
+	if err != nil {
+		tm.logger.Error("Error fetching token lifetime for new token", zap.Error(err))
+		return "", tokenlifetime, utils.Error{Description: "Error fetching token lifetime", BackendError: err.Error(), Err: err}
+	}
+	if _, err = token.CheckTokenLifeTimeWithPolicy(tokenResponse.AccessToken, tm.tokenLifetimePolicy()); err != nil {
+		tm.logger.Warn("Fresh token does not meet the token lifetime policy, it will be refreshed on the next call",
+			zap.Uint64("token-life-time-in-seconds", tokenlifetime), zap.Error(err))
+	}
+	tm.token = tokenResponse.AccessToken
+	tm.recorder.SetTokenLifetime(tm.labels, time.Duration(tokenlifetime)*time.Second)
+	tm.audit(tm.token, tokenlifetime, reasonForCall, false)
+
+	tm.logger.Info("Fetched fresh iam token", zap.Uint64("token-life-time-in-seconds", tokenlifetime))
+	return tm.token, tokenlifetime, nil
+}
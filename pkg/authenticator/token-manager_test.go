@@ -0,0 +1,386 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/secret-utils-lib/pkg/audit"
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/metrics"
+	"github.com/IBM/secret-utils-lib/pkg/token"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRecorder counts the metrics recorded per auth type.
+type fakeRecorder struct {
+	mutex       sync.Mutex
+	labels      []metrics.Labels
+	cacheHits   int
+	cacheMisses int
+	iamRequests map[string]int
+	iamErrors   int
+	retries     int
+	fallbacks   int
+	lifetime    time.Duration
+}
+
+func newFakeRecorder() *fakeRecorder {
+	return &fakeRecorder{iamRequests: make(map[string]int)}
+}
+
+func (fr *fakeRecorder) record(labels metrics.Labels, update func()) {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	fr.labels = append(fr.labels, labels)
+	update()
+}
+
+func (fr *fakeRecorder) CacheHit(labels metrics.Labels) {
+	fr.record(labels, func() { fr.cacheHits++ })
+}
+
+func (fr *fakeRecorder) CacheMiss(labels metrics.Labels) {
+	fr.record(labels, func() { fr.cacheMisses++ })
+}
+
+func (fr *fakeRecorder) ObserveIAMRequest(labels metrics.Labels, endpoint string, duration time.Duration, err error) {
+	fr.record(labels, func() {
+		fr.iamRequests[endpoint]++
+		if err != nil {
+			fr.iamErrors++
+		}
+	})
+}
+
+func (fr *fakeRecorder) Retry(labels metrics.Labels) {
+	fr.record(labels, func() { fr.retries++ })
+}
+
+func (fr *fakeRecorder) Fallback(labels metrics.Labels) {
+	fr.record(labels, func() { fr.fallbacks++ })
+}
+
+func (fr *fakeRecorder) SetTokenLifetime(labels metrics.Labels, lifetime time.Duration) {
+	fr.record(labels, func() { fr.lifetime = lifetime })
+}
+
+func TestGetTokenMetrics(t *testing.T) {
+	server := newStubIAM(t, http.StatusOK, fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":3600,"expiration":%d}`,
+		newTestToken(t, time.Hour), time.Now().Add(time.Hour).Unix()))
+	defer server.Close()
+
+	testcases := []struct {
+		testcasename     string
+		podIdentity      bool
+		expectedAuthType string
+	}{
+		{
+			testcasename:     "API key",
+			expectedAuthType: "iam",
+		},
+		{
+			testcasename:     "Trusted profile",
+			podIdentity:      true,
+			expectedAuthType: "pod-identity",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			recorder := newFakeRecorder()
+			auth := newTestAuthenticator(t, testcase.podIdentity, server.URL)
+			auth.SetMetricsRecorder(recorder, "vpc")
+
+			_, _, err := auth.GetToken(false)
+			assert.Nil(t, err)
+			_, _, err = auth.GetToken(false)
+			assert.Nil(t, err)
+			// A forced refresh is neither a hit nor a miss
+			_, _, err = auth.GetToken(true)
+			assert.Nil(t, err)
+
+			assert.Equal(t, 1, recorder.cacheMisses)
+			assert.Equal(t, 1, recorder.cacheHits)
+			assert.Equal(t, map[string]int{server.URL: 2}, recorder.iamRequests)
+			assert.Equal(t, 0, recorder.iamErrors)
+			assert.True(t, recorder.lifetime > 50*time.Minute)
+			for _, labels := range recorder.labels {
+				assert.Equal(t, metrics.Labels{AuthType: testcase.expectedAuthType, Provider: "vpc"}, labels)
+			}
+		})
+	}
+}
+
+func TestNewAuthenticatorMetricsLabels(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	server := newStubIAM(t, http.StatusOK, fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":3600,"expiration":%d}`,
+		newTestToken(t, time.Hour), time.Now().Add(time.Hour).Unix()))
+	defer server.Close()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	testcases := []struct {
+		testcasename     string
+		fakeAuthType     string
+		secretDataPath   string
+		expectedAuthType string
+	}{
+		{
+			testcasename:     "ibm-cloud-credentials",
+			fakeAuthType:     utils.IAM,
+			secretDataPath:   "secrets/ibm-cloud-credentials/iam-cloud-provider.env",
+			expectedAuthType: utils.IAM,
+		},
+		{
+			testcasename:     "storage-secret-store",
+			fakeAuthType:     utils.DEFAULT,
+			secretDataPath:   "test-fixtures/valid/vpc-gen2/prod/slclient.toml",
+			expectedAuthType: utils.DEFAULT,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			kc, _ := k8s_utils.FakeGetk8sClientSet()
+			if err := k8s_utils.FakeCreateSecret(kc, testcase.fakeAuthType, filepath.Join(pwd, "..", "..", testcase.secretDataPath)); err != nil {
+				t.Fatalf("Failed to create secret, error: %v", err)
+			}
+
+			recorder := newFakeRecorder()
+			auth, authType, err := NewAuthenticatorWithOptions(logger, kc, Options{Metrics: recorder, TokenExchangeURL: server.URL})
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedAuthType, authType)
+
+			_, _, err = auth.GetToken(false)
+			assert.Nil(t, err)
+			assert.NotEmpty(t, recorder.labels)
+			for _, labels := range recorder.labels {
+				assert.Equal(t, metrics.Labels{AuthType: testcase.expectedAuthType, Provider: utils.VPC}, labels)
+			}
+		})
+	}
+}
+
+func TestRequestTokenWithMetrics(t *testing.T) {
+	recorder := newFakeRecorder()
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	auth := NewIamAuthenticator("api-key", logger)
+	auth.SetURL("https://private.iam.cloud.ibm.com", false)
+	auth.SetMetricsRecorder(recorder, "vpc")
+
+	attempts := 0
+	request := auth.instrumentRequest(context.Background(), auth, func() (*core.IamTokenServerResponse, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("timeout")
+		}
+		return &core.IamTokenServerResponse{}, nil
+	}, false)
+	for i := 0; i < 3; i++ {
+		_, _ = request()
+	}
+
+	assert.Equal(t, 2, recorder.retries)
+	assert.Equal(t, 2, recorder.iamErrors)
+	assert.Equal(t, map[string]int{"https://private.iam.cloud.ibm.com": 3}, recorder.iamRequests)
+}
+
+// fakeAuditSink keeps the events recorded in memory.
+type fakeAuditSink struct {
+	mutex  sync.Mutex
+	events []audit.Event
+}
+
+func (fs *fakeAuditSink) Record(event audit.Event) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	fs.events = append(fs.events, event)
+	return nil
+}
+
+func TestGetTokenAudit(t *testing.T) {
+	server := newStubIAM(t, http.StatusOK, fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":3600,"expiration":%d}`,
+		newTestToken(t, time.Hour), time.Now().Add(time.Hour).Unix()))
+	defer server.Close()
+
+	sink := &fakeAuditSink{}
+	auth := newTestAuthenticator(t, true, server.URL)
+	auth.SetAuditSink(sink, "vpc-block-csi-driver")
+
+	_, _, err := auth.GetToken(true, "CreateVolume")
+	assert.Nil(t, err)
+	_, _, err = auth.GetToken(false)
+	assert.Nil(t, err)
+
+	assert.Len(t, sink.events, 2)
+	for i, event := range sink.events {
+		assert.Equal(t, "vpc-block-csi-driver", event.Caller)
+		assert.Equal(t, "pod-identity", event.AuthType)
+		assert.Equal(t, "ServiceId-1234", event.Subject)
+		assert.Equal(t, "iam-ServiceId-1234", event.IAMID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), event.Expiry, time.Minute)
+		assert.Equal(t, i == 1, event.CacheHit)
+	}
+	assert.Equal(t, "CreateVolume", sink.events[0].Reason)
+	assert.Equal(t, "", sink.events[1].Reason)
+
+	// Failures are not audited.
+	auth.SetURL("http://127.0.0.1:1", true)
+	_, _, err = auth.GetToken(true, "DeleteVolume")
+	assert.NotNil(t, err)
+	assert.Len(t, sink.events, 2)
+}
+
+func TestGetTokenClaims(t *testing.T) {
+	var requests int32
+	iamToken := newTestToken(t, time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":3600,"expiration":%d}`,
+			iamToken, time.Now().Add(time.Hour).Unix())))
+	}))
+	defer server.Close()
+
+	for _, podIdentity := range []bool{false, true} {
+		atomic.StoreInt32(&requests, 0)
+		auth := newTestAuthenticator(t, podIdentity, server.URL)
+
+		_, err := auth.GetTokenClaims()
+		var claimsErr utils.Error
+		assert.True(t, errors.As(err, &claimsErr))
+		assert.Equal(t, utils.ErrNoCachedToken, claimsErr.Description)
+
+		_, _, err = auth.GetToken(false)
+		assert.Nil(t, err)
+
+		claims, err := auth.GetTokenClaims()
+		assert.Nil(t, err)
+		assert.Equal(t, "iam-ServiceId-1234", claims.IAMID)
+		assert.Equal(t, "ServiceId-1234", claims.Subject)
+		assert.Equal(t, "ServiceId", claims.SubType)
+		assert.Equal(t, "account-1234", claims.AccountID())
+		assert.Equal(t, []string{"ibm", "openid"}, claims.Scopes())
+		assert.WithinDuration(t, time.Now(), claims.IssuedAt(), time.Minute)
+
+		// The claims are read from the token in cache.
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	}
+}
+
+func TestTokenLifetimePolicy(t *testing.T) {
+	// A token with 5 minutes remaining out of 60.
+	now := time.Now()
+	iamToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iat": now.Add(-55 * time.Minute).Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+	assert.Nil(t, err)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":300,"expiration":%d}`,
+			iamToken, now.Add(5*time.Minute).Unix())))
+	}))
+	defer server.Close()
+
+	testcases := []struct {
+		testcasename     string
+		policy           *token.LifetimePolicy
+		tokenExpirydiff  uint64
+		expectedRequests int32
+	}{
+		{
+			testcasename:     "Minimum remaining lifetime met",
+			policy:           &token.LifetimePolicy{MinRemaining: time.Minute},
+			expectedRequests: 1,
+		},
+		{
+			testcasename:     "Minimum remaining lifetime not met",
+			policy:           &token.LifetimePolicy{MinRemaining: 10 * time.Minute},
+			expectedRequests: 2,
+		},
+		{
+			testcasename:     "Percent of lifetime met",
+			policy:           &token.LifetimePolicy{MinRemainingPercent: 5},
+			expectedRequests: 1,
+		},
+		{
+			testcasename:     "Default percent of lifetime not met",
+			expectedRequests: 2,
+		},
+		{
+			testcasename:     "Policy longer than the token lifetime",
+			policy:           &token.LifetimePolicy{MinRemainingPercent: 99},
+			expectedRequests: 2,
+		},
+		{
+			testcasename:     "Global fallback met",
+			tokenExpirydiff:  60,
+			expectedRequests: 1,
+		},
+		{
+			testcasename:     "Policy takes precedence over the global",
+			policy:           &token.LifetimePolicy{MinRemaining: time.Minute},
+			tokenExpirydiff:  600,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			defer func(diff uint64) { utils.TokenExpirydiff = diff }(utils.TokenExpirydiff)
+			utils.TokenExpirydiff = testcase.tokenExpirydiff
+			atomic.StoreInt32(&requests, 0)
+
+			auth := newTestAuthenticator(t, false, server.URL)
+			if testcase.policy != nil {
+				auth.SetTokenLifetimePolicy(*testcase.policy)
+			}
+
+			// A fresh token is returned even if it does not meet the policy, it is refreshed on the next call
+			for i := 0; i < 2; i++ {
+				iamTokenReturned, _, err := auth.GetToken(false)
+				assert.Nil(t, err)
+				assert.Equal(t, iamToken, iamTokenReturned)
+			}
+			assert.Equal(t, testcase.expectedRequests, atomic.LoadInt32(&requests))
+		})
+	}
+}
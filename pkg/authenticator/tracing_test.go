@@ -0,0 +1,135 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestSpanRecorder sets a global tracer provider recording the spans in memory, until the test ends.
+func newTestSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestTracingSpans(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+	spanRecorder := newTestSpanRecorder(t)
+
+	iamToken := newTestToken(t, time.Hour)
+	server := newStubIAM(t, http.StatusOK, fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":3600,"expiration":%d}`,
+		iamToken, time.Now().Add(time.Hour).Unix()))
+	defer server.Close()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	secretFilePath := filepath.Join(pwd, "..", "..", "secrets/ibm-cloud-credentials/iam-cloud-provider.env")
+	if err := k8s_utils.FakeCreateSecret(kc, utils.IAM, secretFilePath); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+
+	missingSource := CredentialSource{
+		SecretSource: k8s_utils.SecretSource{Namespace: "tenant-b", SecretName: "tenant-credentials", Key: "tenant.env"},
+		Format:       FormatIBMCloudCredentials,
+	}
+	opts := Options{
+		CredentialSources: append([]CredentialSource{missingSource}, DefaultCredentialSources()...),
+		TokenExchangeURL:  server.URL,
+	}
+
+	auth, authType, err := NewAuthenticatorWithContext(context.Background(), logger, kc, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, utils.IAM, authType)
+	_, _, err = auth.GetToken(true)
+	assert.Nil(t, err)
+	// The span of the caller, for example the CreateVolume request of a driver
+	ctx, callerSpan := otel.Tracer("test").Start(context.Background(), "CreateVolume")
+	_, _, err = auth.GetTokenWithContext(ctx, false)
+	assert.Nil(t, err)
+	callerSpan.End()
+
+	spans := spanRecorder.Ended()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"GetSecretData", "GetSecretData", "ParseCredentials", "NewAuthenticator", "RequestToken", "GetToken", "GetToken", "CreateVolume"}, names)
+
+	// The secret reads and the parsing are children of NewAuthenticator, the token request is a child of GetToken.
+	newAuthenticatorSpan, getTokenSpan := spans[3], spans[5]
+	for _, span := range spans[:3] {
+		assert.Equal(t, newAuthenticatorSpan.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.Equal(t, getTokenSpan.SpanContext().SpanID(), spans[4].Parent().SpanID())
+	// GetToken without a context is a root span, GetTokenWithContext is a child of the span of the caller.
+	assert.False(t, getTokenSpan.Parent().IsValid())
+	assert.Equal(t, spans[7].SpanContext().SpanID(), spans[6].Parent().SpanID())
+	assert.Equal(t, spans[7].SpanContext().TraceID(), spans[6].SpanContext().TraceID())
+
+	attributes := func(span sdktrace.ReadOnlySpan) map[string]string {
+		attrs := make(map[string]string)
+		for _, attr := range span.Attributes() {
+			attrs[string(attr.Key)] = attr.Value.Emit()
+		}
+		return attrs
+	}
+	assert.Equal(t, "tenant-credentials", attributes(spans[0])[string(utils.AttrSecretName)])
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, utils.IAM, attributes(newAuthenticatorSpan)[string(utils.AttrAuthType)])
+	assert.Equal(t, map[string]string{
+		string(utils.AttrAuthType): utils.IAM,
+		string(utils.AttrEndpoint): server.URL,
+		string(utils.AttrAttempt):  "1",
+		string(utils.AttrFallback): "false",
+	}, attributes(spans[4]))
+	assert.Equal(t, "false", attributes(getTokenSpan)[string(utils.AttrCacheHit)])
+	assert.Equal(t, "true", attributes(spans[6])[string(utils.AttrCacheHit)])
+
+	// Secret values are never recorded.
+	for _, span := range spans {
+		for key, value := range attributes(span) {
+			assert.NotContains(t, value, auth.GetSecret(), "span %s, attribute %s", span.Name(), key)
+			assert.NotContains(t, value, iamToken, "span %s, attribute %s", span.Name(), key)
+		}
+		for _, event := range span.Events() {
+			for _, attr := range event.Attributes {
+				assert.False(t, strings.Contains(attr.Value.Emit(), auth.GetSecret()), "span %s, event %s", span.Name(), event.Name)
+			}
+		}
+	}
+}
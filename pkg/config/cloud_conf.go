@@ -18,8 +18,11 @@ package config
 
 import (
 	"encoding/json"
+	"regexp"
+	"strings"
 
 	k8s_utils "github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
 	"go.uber.org/zap"
 )
 
@@ -28,6 +31,7 @@ const (
 	cloudConfData = "cloud-conf.json"
 )
 
+// CloudConf is the data of the cloud-conf config map.
 type CloudConf struct {
 	Region                   string `json:"region"`
 	RiaasEndpoint            string `json:"riaas_endpoint"`
@@ -36,16 +40,114 @@ type CloudConf struct {
 	PrivateContainerAPIRoute string `json:"containers_api_route_private"`
 	ResourceGroupID          string `json:"resource_group_id"`
 	TokenExchangeURL         string `json:"token_exchange_url"`
+	// Environment overrides the environment detected from the endpoints and the master URL, one of prod, stage, custom.
+	Environment Environment `json:"environment,omitempty"`
 }
 
-// GetCloudConf ...
+// requiredCloudConfFields are the fields required per cluster type.
+var requiredCloudConfFields = map[string][]string{
+	utils.VPCGen2:          {"region", "riaas_endpoint", "riaas_private_endpoint", "resource_group_id"},
+	utils.Cruiser:          {"region", "containers_api_route"},
+	utils.SatelliteCruiser: {"containers_api_route"},
+}
+
+// fields returns the value of every field, by json name.
+func (cc CloudConf) fields() map[string]string {
+	return map[string]string{
+		"region":                       cc.Region,
+		"riaas_endpoint":               cc.RiaasEndpoint,
+		"riaas_private_endpoint":       cc.PrivateRIAASEndpoint,
+		"containers_api_route":         cc.ContainerAPIRoute,
+		"containers_api_route_private": cc.PrivateContainerAPIRoute,
+		"resource_group_id":            cc.ResourceGroupID,
+		"token_exchange_url":           cc.TokenExchangeURL,
+		"environment":                  string(cc.Environment),
+	}
+}
+
+// Validate checks the cloud-conf of a cluster of type clusterType (vpc-gen2_cruiser, cruiser, satellite_cruiser).
+// - The fields required by the cluster type are set.
+// - URLs, if set, are absolute https URLs (token_exchange_url is optional, see ResolveTokenExchangeURL).
+// - The hosts of the riaas endpoints and containers api routes belong to the region (global hosts are not checked).
+// - environment, if set, is one of prod, stage, custom.
+// The error returned is a ValidationError listing every invalid field.
+func (cc CloudConf) Validate(clusterType string) error {
+	var errs fieldErrors
+	fields := cc.fields()
+
+	for _, field := range requiredCloudConfFields[clusterType] {
+		errs.required(field, fields[field])
+	}
+
+	regionalFields := []string{"riaas_endpoint", "riaas_private_endpoint", "containers_api_route", "containers_api_route_private"}
+	for _, field := range append(regionalFields, "token_exchange_url") {
+		if fields[field] == "" {
+			continue
+		}
+		u := errs.httpsURL(field, fields[field])
+		if u == nil || cc.Region == "" || field == "token_exchange_url" {
+			continue
+		}
+		if !hostInRegion(u.Hostname(), cc.Region) {
+			errs.add(field, fields[field], "host does not belong to region "+cc.Region)
+		}
+	}
+
+	if cc.Environment != "" && !cc.Environment.IsValid() {
+		errs.add("environment", string(cc.Environment), "must be one of prod, stage, custom")
+	}
+
+	return errs.err()
+}
+
+// regionLabel matches the host labels naming a region, for example us-south in us-south.iaas.cloud.ibm.com
+// or us-south-stage01 in us-south-stage01.iaasdev.cloud.ibm.com.
+var regionLabel = regexp.MustCompile(`^[a-z]{2}-[a-z]{2,5}(-|$)`)
+
+// hostInRegion checks if host belongs to region, that is one of its labels is the region, for example
+// private.us-south.containers.cloud.ibm.com, or starts with <region>-, for example us-south-stage01.iaasdev.cloud.ibm.com.
+// Global hosts, none of whose labels name a region (for example containers.cloud.ibm.com), belong to every region.
+func hostInRegion(host, region string) bool {
+	global := true
+	for _, label := range strings.Split(host, ".") {
+		if label == region || strings.HasPrefix(label, region+"-") {
+			return true
+		}
+		if regionLabel.MatchString(label) {
+			global = false
+		}
+	}
+	return global
+}
+
+// GetCloudConf reads the cloud-conf config map, without validating it.
 func GetCloudConf(logger *zap.Logger, k8sClient k8s_utils.KubernetesClient) (CloudConf, error) {
 	var cloudConf CloudConf
 	data, err := k8s_utils.GetConfigMapData(k8sClient, cloudConfCM, cloudConfData)
 	if err != nil {
+		logger.Info("Unable to fetch cloud-conf", zap.Error(err))
 		return cloudConf, err
 	}
 
 	err = json.Unmarshal([]byte(data), &cloudConf)
-	return cloudConf, err
+	if err != nil {
+		logger.Error("Error parsing cloud-conf", zap.Error(err))
+		return cloudConf, utils.Error{Description: utils.ErrParsingCloudConf, BackendError: err.Error(), Code: utils.ConfigParse, Err: err}
+	}
+	return cloudConf, nil
+}
+
+// LoadCloudConf reads the cloud-conf config map and validates it for a cluster of type clusterType,
+// see CloudConf.Validate. errors.As can be used to reach the ValidationError.
+func LoadCloudConf(logger *zap.Logger, k8sClient k8s_utils.KubernetesClient, clusterType string) (CloudConf, error) {
+	cloudConf, err := GetCloudConf(logger, k8sClient)
+	if err != nil {
+		return cloudConf, err
+	}
+
+	if err = cloudConf.Validate(clusterType); err != nil {
+		logger.Error("Invalid cloud-conf", zap.String("cluster-type", clusterType), zap.Error(err))
+		return cloudConf, utils.Error{Description: utils.ErrInvalidCloudConf, BackendError: err.Error(), Code: utils.ConfigParse, Err: err}
+	}
+	return cloudConf, nil
 }
@@ -0,0 +1,299 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var validVPCCloudConf = CloudConf{
+	Region:                   "us-south",
+	RiaasEndpoint:            "https://us-south.iaas.cloud.ibm.com",
+	PrivateRIAASEndpoint:     "https://us-south.private.iaas.cloud.ibm.com",
+	ContainerAPIRoute:        "https://us-south.containers.cloud.ibm.com",
+	PrivateContainerAPIRoute: "https://private.us-south.containers.cloud.ibm.com",
+	ResourceGroupID:          "resource-group-id",
+	TokenExchangeURL:         "https://private.iam.cloud.ibm.com",
+}
+
+func TestCloudConfValidate(t *testing.T) {
+	testcases := []struct {
+		testcasename   string
+		cloudConf      func(cc CloudConf) CloudConf
+		clusterType    string
+		expectedFields []string
+	}{
+		{
+			testcasename: "Valid VPC cloud-conf",
+			cloudConf:    func(cc CloudConf) CloudConf { return cc },
+			clusterType:  utils.VPCGen2,
+		},
+		{
+			testcasename:   "Empty cloud-conf for VPC",
+			cloudConf:      func(cc CloudConf) CloudConf { return CloudConf{} },
+			clusterType:    utils.VPCGen2,
+			expectedFields: []string{"region", "riaas_endpoint", "riaas_private_endpoint", "resource_group_id"},
+		},
+		{
+			testcasename:   "Empty cloud-conf for classic",
+			cloudConf:      func(cc CloudConf) CloudConf { return CloudConf{} },
+			clusterType:    utils.Cruiser,
+			expectedFields: []string{"region", "containers_api_route"},
+		},
+		{
+			testcasename: "Satellite cloud-conf without region",
+			cloudConf: func(cc CloudConf) CloudConf {
+				return CloudConf{ContainerAPIRoute: "https://us-east.containers.cloud.ibm.com", TokenExchangeURL: "https://iam.cloud.ibm.com"}
+			},
+			clusterType: utils.SatelliteCruiser,
+		},
+		{
+			testcasename: "Token exchange URL not set",
+			cloudConf: func(cc CloudConf) CloudConf {
+				cc.TokenExchangeURL = ""
+				return cc
+			},
+			clusterType: utils.VPCGen2,
+		},
+		{
+			testcasename: "Unknown cluster type, sample cloud-conf.yaml",
+			cloudConf:    func(cc CloudConf) CloudConf { return CloudConf{Region: "region"} },
+		},
+		{
+			testcasename: "http scheme",
+			cloudConf: func(cc CloudConf) CloudConf {
+				cc.TokenExchangeURL = "http://private.iam.cloud.ibm.com"
+				return cc
+			},
+			clusterType:    utils.VPCGen2,
+			expectedFields: []string{"token_exchange_url"},
+		},
+		{
+			testcasename: "Relative URL",
+			cloudConf: func(cc CloudConf) CloudConf {
+				cc.RiaasEndpoint = "us-south.iaas.cloud.ibm.com"
+				return cc
+			},
+			clusterType:    utils.VPCGen2,
+			expectedFields: []string{"riaas_endpoint"},
+		},
+		{
+			testcasename: "Unknown environment",
+			cloudConf: func(cc CloudConf) CloudConf {
+				cc.Environment = "qa"
+				return cc
+			},
+			clusterType:    utils.VPCGen2,
+			expectedFields: []string{"environment"},
+		},
+		{
+			testcasename: "Endpoints of another region",
+			cloudConf: func(cc CloudConf) CloudConf {
+				cc.Region = "eu-de"
+				return cc
+			},
+			clusterType:    utils.VPCGen2,
+			expectedFields: []string{"riaas_endpoint", "riaas_private_endpoint", "containers_api_route", "containers_api_route_private"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			err := testcase.cloudConf(validVPCCloudConf).Validate(testcase.clusterType)
+			if len(testcase.expectedFields) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+
+			var validationErr ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			fields := make([]string, 0, len(validationErr.Errors))
+			for _, fieldErr := range validationErr.Errors {
+				fields = append(fields, fieldErr.Field)
+			}
+			assert.Equal(t, testcase.expectedFields, fields)
+		})
+	}
+}
+
+func TestLoadCloudConf(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testcases := []struct {
+		testcasename string
+		data         *string
+		expectedCode utils.ErrorCode
+		expectError  bool
+	}{
+		{
+			testcasename: "Valid cloud-conf",
+			data:         stringPtr(`{"region":"us-south","riaas_endpoint":"https://us-south.iaas.cloud.ibm.com","riaas_private_endpoint":"https://us-south.private.iaas.cloud.ibm.com","resource_group_id":"rg","token_exchange_url":"https://private.iam.cloud.ibm.com"}`),
+		},
+		{
+			testcasename: "Invalid cloud-conf",
+			data:         stringPtr(`{"region":"us-south","token_exchange_url":"https://private.iam.cloud.ibm.com"}`),
+			expectedCode: utils.ConfigParse,
+			expectError:  true,
+		},
+		{
+			testcasename: "Malformed cloud-conf",
+			data:         stringPtr(`{"region":`),
+			expectedCode: utils.ConfigParse,
+			expectError:  true,
+		},
+		{
+			testcasename: "Missing cloud-conf",
+			expectError:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			kc, _ := k8s_utils.FakeGetk8sClientSet()
+			if testcase.data != nil {
+				createCloudConf(t, kc, *testcase.data)
+			}
+
+			cloudConf, err := LoadCloudConf(logger, kc, utils.VPCGen2)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expectedCode, utils.GetErrorCode(err))
+			if !testcase.expectError {
+				assert.Equal(t, "us-south", cloudConf.Region)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// createCloudConf creates the cloud-conf config map with data.
+func createCloudConf(t *testing.T, kc k8s_utils.KubernetesClient, data string) {
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cloudConfCM}, Data: map[string]string{cloudConfData: data}}
+	if _, err := kc.Clientset.CoreV1().ConfigMaps(kc.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create cloud-conf, error: %v", err)
+	}
+}
+
+func TestCloudConfValidateFixtures(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testcases := []struct {
+		testcasename     string
+		secretconfigpath string
+		region           string
+		clusterType      string
+		expectedFields   []string
+	}{
+		{
+			testcasename:     "VPC prod",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/prod/slclient.toml",
+			region:           "au-syd",
+			clusterType:      utils.VPCGen2,
+		},
+		{
+			testcasename:     "VPC stage, global containers route and stage riaas hosts",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/stage/slclient.toml",
+			region:           "us-south",
+			clusterType:      utils.VPCGen2,
+		},
+		{
+			testcasename:     "VPC dev",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/dev/slclient.toml",
+			region:           "us-south",
+			clusterType:      utils.VPCGen2,
+		},
+		{
+			testcasename:     "Classic prod",
+			secretconfigpath: "test-fixtures/valid/classic/prod/slclient.toml",
+			region:           "us-south",
+			clusterType:      utils.Cruiser,
+		},
+		{
+			testcasename:     "Classic stage, global containers route",
+			secretconfigpath: "test-fixtures/valid/classic/stage/slclient.toml",
+			region:           "us-south",
+			clusterType:      utils.Cruiser,
+		},
+		{
+			testcasename:     "VPC stage endpoints in another region",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/stage/slclient.toml",
+			region:           "eu-de",
+			clusterType:      utils.VPCGen2,
+			expectedFields:   []string{"riaas_endpoint", "riaas_private_endpoint"},
+		},
+		{
+			testcasename:     "VPC prod endpoints in another region",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/prod/slclient.toml",
+			region:           "us-south",
+			clusterType:      utils.VPCGen2,
+			expectedFields:   []string{"riaas_endpoint", "riaas_private_endpoint", "containers_api_route", "containers_api_route_private"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			pwd, err := os.Getwd()
+			if err != nil {
+				t.Errorf("Failed to get current working directory, error: %v", err)
+			}
+			byteData, err := ioutil.ReadFile(filepath.Join(pwd, "..", "..", testcase.secretconfigpath))
+			if err != nil {
+				t.Errorf("Failed to read %s, error: %v", testcase.secretconfigpath, err)
+			}
+			conf, err := ParseConfig(logger, string(byteData))
+			assert.Nil(t, err)
+
+			// cloud-conf holding the endpoints of slclient.toml
+			cloudConf := CloudConf{
+				Region:                   testcase.region,
+				RiaasEndpoint:            conf.GetVPC().G2EndpointURL,
+				PrivateRIAASEndpoint:     conf.GetVPC().G2EndpointPrivateURL,
+				ContainerAPIRoute:        conf.GetBluemix().APIEndpointURL,
+				PrivateContainerAPIRoute: conf.GetBluemix().PrivateAPIRoute,
+				ResourceGroupID:          conf.GetVPC().G2ResourceGroupID,
+				TokenExchangeURL:         conf.GetBluemix().IamURL,
+			}
+			err = cloudConf.Validate(testcase.clusterType)
+			if len(testcase.expectedFields) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			var validationErr ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			fields := make([]string, 0, len(validationErr.Errors))
+			for _, fe := range validationErr.Errors {
+				fields = append(fields, fe.Field)
+			}
+			assert.Equal(t, testcase.expectedFields, fields)
+		})
+	}
+}
@@ -18,6 +18,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
@@ -38,12 +39,24 @@ const (
 	constTrue = "True"
 )
 
-// ClusterConfig ...
+// ClusterConfig is the data of cluster-config.json in the cluster-info config map.
+// Fields not defined here are kept, see ExtraField.
 type ClusterConfig struct {
 	ClusterID       string `json:"cluster_id"`
+	ClusterName     string `json:"cluster_name,omitempty"`
+	Name            string `json:"name,omitempty"`
 	MasterURL       string `json:"master_url"`
+	MasterPublicURL string `json:"master_public_url,omitempty"`
 	ClusterProvider string `json:"cluster_provider"`
 	ClusterType     string `json:"cluster_type"`
+	ClusterPayTier  string `json:"cluster_pay_tier,omitempty"`
+	AccountID       string `json:"account_id,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Datacenter      string `json:"datacenter,omitempty"`
+	CRN             string `json:"crn,omitempty"`
+
+	// extra holds the fields of cluster-config.json not defined above, by name.
+	extra map[string]json.RawMessage
 }
 
 // GetClusterInfo ...
@@ -58,122 +71,157 @@ func GetClusterInfo(kc k8s_utils.KubernetesClient, logger *zap.Logger) (ClusterC
 	err = json.Unmarshal([]byte(data), &cc)
 	if err != nil {
 		logger.Error("Error fetching cluster-info configmap", zap.Error(err))
-		return cc, utils.Error{Description: utils.ErrFetchingClusterConfig, BackendError: err.Error()}
+		return cc, utils.Error{Description: utils.ErrFetchingClusterConfig, BackendError: err.Error(), Code: utils.ConfigParse, Err: err}
 	}
 
 	return cc, nil
 }
 
+// TokenExchangeURLOptions ...
+type TokenExchangeURLOptions struct {
+	// SecretStoreSources are the slclient.toml sources tried in order, the first source that can be read is used.
+	// If empty, slclient.toml in storage-secret-store in the namespace of the k8s client is used.
+	SecretStoreSources []k8s_utils.SecretSource
+}
+
+// secretStoreSources ...
+func (opts TokenExchangeURLOptions) secretStoreSources() []k8s_utils.SecretSource {
+	if len(opts.SecretStoreSources) == 0 {
+		return []k8s_utils.SecretSource{{SecretName: utils.STORAGE_SECRET_STORE_SECRET, Key: utils.SECRET_STORE_FILE}}
+	}
+	return opts.SecretStoreSources
+}
+
 // FrameTokenExchangeURL ...
-func FrameTokenExchangeURL(kc k8s_utils.KubernetesClient, providerType string, logger *zap.Logger) (string, bool) {
+func FrameTokenExchangeURL(kc k8s_utils.KubernetesClient, providerType string, logger *zap.Logger, optionalArgs ...TokenExchangeURLOptions) (string, bool) {
+	report := ResolveTokenExchangeURL(kc, providerType, logger, optionalArgs...)
+	return report.URL, report.IsURLProvided
+}
 
-	var isURLprovided = true
+// ResolveTokenExchangeURL is FrameTokenExchangeURL, returning a report of the sources consulted
+// and the rule which chose the URL, see TokenExchangeURLReport.
+func ResolveTokenExchangeURL(kc k8s_utils.KubernetesClient, providerType string, logger *zap.Logger, optionalArgs ...TokenExchangeURLOptions) TokenExchangeURLReport {
+
+	var opts TokenExchangeURLOptions
+	if len(optionalArgs) != 0 {
+		opts = optionalArgs[0]
+	}
+
+	var report TokenExchangeURLReport
 	// Fetch token exchange URL from cloud-conf
 	cloudConf, err := GetCloudConf(logger, kc)
+	report.addStep(SourceCloudConf, cloudConfCM+"/"+cloudConfData, cloudConf.TokenExchangeURL, err)
 	if err == nil && cloudConf.TokenExchangeURL != "" {
-		return cloudConf.TokenExchangeURL + tokenExchangePath, isURLprovided
+		return report.resolved(cloudConf.TokenExchangeURL+tokenExchangePath, true, RuleCloudConf)
+	}
+	// The environment in cloud-conf overrides the one detected from slclient.toml and cluster-info
+	envOverride := cloudConf.Environment
+	if envOverride != "" {
+		report.addStep(SourceCloudConf, cloudConfCM+"/"+cloudConfData+" environment", string(envOverride), nil)
 	}
 
 	logger.Info("Unable to fetch token exchange URL from cloud-conf")
 	clusterInfo, err := GetClusterInfo(kc, logger)
+	report.addStep(SourceClusterInfo, clusterInfoCM+"/"+clusterConfigName, clusterInfoSummary(clusterInfo), err)
 	if err != nil {
 		logger.Error("Error fetching cluster info", zap.Error(err))
-		return (utils.ProdPrivateIAMURL + tokenExchangePath), !isURLprovided
+		return report.resolved(utils.ProdPrivateIAMURL+tokenExchangePath, false, RuleClusterInfoUnavailable)
 	}
 
-	secret, err := k8s_utils.GetSecretData(kc, utils.STORAGE_SECRET_STORE_SECRET, utils.SECRET_STORE_FILE)
-	if err == nil {
-		if secretConfig, err := ParseConfig(logger, secret); err == nil {
-			url, isURLprovided, err := GetTokenExchangeURLfromStorageSecretStore(clusterInfo, *secretConfig, providerType)
-			if err == nil {
-				return url, isURLprovided
-			}
+	for _, source := range opts.secretStoreSources() {
+		detail := fmt.Sprintf("%s/%s/%s (provider %s)", source.Namespace, source.SecretName, source.Key, providerType)
+		secret, err := k8s_utils.GetSecretDataFromSource(kc, source)
+		if err != nil {
+			logger.Info("Unable to fetch secret", zap.String("namespace", source.Namespace), zap.String("secret-name", source.SecretName), zap.String("key-name", source.Key), zap.Error(err))
+			report.addStep(SourceSecretStore, detail, "", err)
+			continue
+		}
+		secretConfig, err := ParseConfig(logger, secret)
+		if err != nil {
+			report.addStep(SourceSecretStore, detail, "", err)
+			break
 		}
+		url, isURLprovided, rule, err := tokenExchangeURLFromSecretStore(clusterInfo, *secretConfig, providerType, envOverride)
+		report.addStep(SourceSecretStore, detail, url, err)
+		if err == nil {
+			return report.resolved(url, isURLprovided, rule)
+		}
+		break
 	}
 
 	logger.Info("Unable to fetch token exchange URL using secret, forming url using cluster info")
-	return FrameTokenExchangeURLFromClusterInfo(clusterInfo, logger)
+	url, isURLprovided, rule := tokenExchangeURLFromClusterInfo(clusterInfo, envOverride, logger)
+	return report.resolved(url, isURLprovided, rule)
 }
 
 // GetTokenExchangeURLfromStorageSecretStore ...
 func GetTokenExchangeURLfromStorageSecretStore(clusterInfo ClusterConfig, config Config, providerType string) (string, bool, error) {
+	url, isURLprovided, _, err := tokenExchangeURLFromSecretStore(clusterInfo, config, providerType, "")
+	return url, isURLprovided, err
+}
+
+// tokenExchangeURLFromSecretStore is GetTokenExchangeURLfromStorageSecretStore, also returning the rule which chose the URL.
+// If envOverride is set, it is used instead of the environment of the token exchange URL in slclient.toml.
+func tokenExchangeURLFromSecretStore(clusterInfo ClusterConfig, config Config, providerType string, envOverride Environment) (string, bool, string, error) {
 
-	// Return Private Prod/Stage IAM URL if the cluster is VPC Gen2
+	if providerType != utils.VPC && providerType != utils.Bluemix && providerType != utils.Softlayer {
+		return "", false, "", utils.Error{Description: utils.ErrInvalidProviderType, BackendError: "provider type: " + providerType}
+	}
+	url := config.TokenExchangeURL(providerType)
+
+	// Return the private URL of the provider if provided, else Private Prod/Stage IAM URL if the cluster is VPC Gen2
 	var isURLprovided = false
-	if GetIAASProvider(clusterInfo) == utils.VPCGen2 {
-		if isEndpointPrivate(config.VPC.G2TokenExchangeURL) {
+	if clusterInfo.Kind().IsVPC() {
+		if isEndpointPrivate(url) {
 			isURLprovided = true
-			return config.VPC.G2TokenExchangeURL + tokenExchangePath, isURLprovided, nil
+			return strings.TrimSuffix(url, tokenExchangePath) + tokenExchangePath, isURLprovided, RuleVPCPrivateURLProvided, nil
 		}
 		isURLprovided = false
-		if isProduction(config.VPC.G2TokenExchangeURL) {
-			return utils.ProdPrivateIAMURL + tokenExchangePath, isURLprovided, nil
+		if environmentOf(url, envOverride) != EnvironmentStage {
+			return utils.ProdPrivateIAMURL + tokenExchangePath, isURLprovided, RuleVPCProdPrivateIAM, nil
 		}
-		return utils.StagePrivateIAMURL + tokenExchangePath, isURLprovided, nil
+		return utils.StagePrivateIAMURL + tokenExchangePath, isURLprovided, RuleVPCStagePrivateIAM, nil
 	}
 
 	// If the cluster is satellite, classic, IPI, return the URL provided in storage-secret-store
 	isURLprovided = true
-	var url string
-	switch providerType {
-	case utils.VPC:
-		url = config.VPC.G2TokenExchangeURL
-	case utils.Bluemix:
-		url = config.Bluemix.IamURL
-	case utils.Softlayer:
-		url = config.Softlayer.SoftlayerTokenExchangeURL
-	}
-
 	if url == "" {
-		return "", isURLprovided, utils.Error{Description: utils.WarnFetchingTokenExchangeURL}
+		return "", isURLprovided, "", utils.Error{Description: utils.WarnFetchingTokenExchangeURL}
 	}
 
-	return url, isURLprovided, nil
+	return url, isURLprovided, RuleProviderURLProvided, nil
 }
 
 // FrameTokenExchangeURLFromClusterInfo ...
 func FrameTokenExchangeURLFromClusterInfo(cc ClusterConfig, logger *zap.Logger) (string, bool) {
+	url, isURLprovided, _ := tokenExchangeURLFromClusterInfo(cc, "", logger)
+	return url, isURLprovided
+}
+
+// tokenExchangeURLFromClusterInfo is FrameTokenExchangeURLFromClusterInfo, also returning the rule which chose the URL.
+// If envOverride is set, it is used instead of the environment of the master URL.
+func tokenExchangeURLFromClusterInfo(cc ClusterConfig, envOverride Environment, logger *zap.Logger) (string, bool, string) {
 
 	var isURLprovided = true
-	isSatellite := IsSatellite(cc, logger)
-	isProd := isProduction(cc.MasterURL)
+	requiresPublicIAM := cc.Kind().RequiresPublicIAM()
+	isProd := environmentOf(cc.MasterURL, envOverride) != EnvironmentStage
 	switch {
-	case isSatellite && isProd:
-		return (utils.ProdPublicIAMURL + tokenExchangePath), isURLprovided
-	case isSatellite && !isProd:
-		return (utils.StagePublicIAMURL + tokenExchangePath), isURLprovided
-	case !isSatellite && isProd:
-		return (utils.ProdPrivateIAMURL + tokenExchangePath), !isURLprovided
-	case !isSatellite && !isProd:
-		return (utils.StagePrivateIAMURL + tokenExchangePath), !isURLprovided
-	}
-
-	return (utils.ProdPrivateIAMURL + tokenExchangePath), !isURLprovided
-}
-
-// isEndpointPrivate determines if the provided url is private or public endpoint
-func isEndpointPrivate(url string) bool {
-	if strings.Contains(url, "private") {
-		return true
+	case requiresPublicIAM && isProd:
+		return (utils.ProdPublicIAMURL + tokenExchangePath), isURLprovided, RuleSatelliteProdPublicIAM
+	case requiresPublicIAM && !isProd:
+		return (utils.StagePublicIAMURL + tokenExchangePath), isURLprovided, RuleSatelliteStagePublicIAM
+	case !requiresPublicIAM && isProd:
+		return (utils.ProdPrivateIAMURL + tokenExchangePath), !isURLprovided, RuleProdPrivateIAM
+	case !requiresPublicIAM && !isProd:
+		return (utils.StagePrivateIAMURL + tokenExchangePath), !isURLprovided, RuleStagePrivateIAM
 	}
-	return false
-}
 
-// isProduction determines if the env in which a pod is deployed is stage or production
-func isProduction(url string) bool {
-	if !strings.Contains(url, "stage") && !strings.Contains(url, "test") {
-		return true
-	}
-	return false
+	return (utils.ProdPrivateIAMURL + tokenExchangePath), !isURLprovided, RuleProdPrivateIAM
 }
 
 // IsSatellite checks if the cluster where the pod is currently running is a satellite cluster or not
 func IsSatellite(cc ClusterConfig, logger *zap.Logger) bool {
-	if cc.ClusterProvider == utils.SatelliteProvider {
-		return true
-	}
-
-	return false
+	return cc.Kind().IsSatellite()
 }
 
 // GetIAASProvider ...
@@ -0,0 +1,157 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// clusterConfigFields are the json names of the fields defined in ClusterConfig.
+var clusterConfigFields = jsonFieldNames(reflect.TypeOf(ClusterConfig{}))
+
+// clusterConfigAlias has the fields of ClusterConfig without its methods, to be used by the json methods.
+type clusterConfigAlias ClusterConfig
+
+// UnmarshalJSON decodes cluster-config.json, keeping the fields not defined in ClusterConfig.
+func (cc *ClusterConfig) UnmarshalJSON(data []byte) error {
+	var alias clusterConfigAlias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name := range clusterConfigFields {
+		delete(fields, name)
+	}
+	alias.extra = nil
+	if len(fields) != 0 {
+		alias.extra = fields
+	}
+
+	*cc = ClusterConfig(alias)
+	return nil
+}
+
+// MarshalJSON encodes the cluster config along with the fields not defined in ClusterConfig.
+func (cc ClusterConfig) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(clusterConfigAlias(cc))
+	if err != nil || len(cc.extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range cc.extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// ExtraField returns the raw json value of a field of cluster-config.json not defined in ClusterConfig.
+func (cc ClusterConfig) ExtraField(name string) (json.RawMessage, bool) {
+	value, ok := cc.extra[name]
+	return value, ok
+}
+
+// ExtraString returns the value of a string field of cluster-config.json not defined in ClusterConfig,
+// false if the field is not present or is not a string.
+func (cc ClusterConfig) ExtraString(name string) (string, bool) {
+	var value string
+	raw, ok := cc.extra[name]
+	if !ok || json.Unmarshal(raw, &value) != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// ExtraFieldNames returns the names of the fields of cluster-config.json not defined in ClusterConfig, sorted.
+func (cc ClusterConfig) ExtraFieldNames() []string {
+	names := make([]string, 0, len(cc.extra))
+	for name := range cc.extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetRegion returns the region of the cluster, from the region field, else from the location in the CRN,
+// else from the master URL (for example us-south in c1.us-south.containers.cloud.ibm.com). Empty if none has a known region.
+func (cc ClusterConfig) GetRegion() string {
+	if cc.Region != "" {
+		return cc.Region
+	}
+	if crn, err := ParseCRN(cc.CRN); err == nil {
+		if _, ok := knownRegions[crn.Location]; ok {
+			return crn.Location
+		}
+	}
+	for _, url := range []string{cc.MasterURL, cc.MasterPublicURL} {
+		info, err := ParseEndpoint(url)
+		if err != nil {
+			continue
+		}
+		for _, label := range strings.Split(info.Host, ".") {
+			if _, ok := knownRegions[label]; ok {
+				return label
+			}
+		}
+	}
+	return ""
+}
+
+// GetAccountID returns the account ID of the cluster, from the account_id field, else from the scope of the CRN.
+func (cc ClusterConfig) GetAccountID() string {
+	if cc.AccountID != "" {
+		return cc.AccountID
+	}
+	if crn, err := ParseCRN(cc.CRN); err == nil {
+		return crn.AccountID()
+	}
+	return ""
+}
+
+// GetCRN parses the CRN of the cluster.
+func (cc ClusterConfig) GetCRN() (CRN, error) {
+	return ParseCRN(cc.CRN)
+}
+
+// jsonFieldNames returns the json names of the exported fields of t.
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	names := make(map[string]struct{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = struct{}{}
+	}
+	return names
+}
@@ -0,0 +1,154 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const fullClusterConfig = `{
+	"cluster_id": "cluster-id",
+	"cluster_name": "cluster-name",
+	"cluster_type": "vpc-gen2_cruiser",
+	"cluster_provider": "",
+	"cluster_pay_tier": "paid",
+	"datacenter": "dal10",
+	"account_id": "account-id",
+	"name": "name",
+	"region": "us-south",
+	"crn": "crn:v1:bluemix:public:containers-kubernetes:us-south:a/account-id:cluster-id::",
+	"master_public_url": "https://c1-e.us-south.containers.cloud.ibm.com:30000",
+	"master_url": "https://c1.us-south.containers.cloud.ibm.com:30000",
+	"worker_pool_count": 2,
+	"ingress_hostname": "cluster.us-south.containers.appdomain.cloud"
+}`
+
+func TestClusterConfigJSON(t *testing.T) {
+	var cc ClusterConfig
+	assert.Nil(t, json.Unmarshal([]byte(fullClusterConfig), &cc))
+
+	assert.Equal(t, "cluster-name", cc.ClusterName)
+	assert.Equal(t, "paid", cc.ClusterPayTier)
+	assert.Equal(t, "dal10", cc.Datacenter)
+	assert.Equal(t, "https://c1-e.us-south.containers.cloud.ibm.com:30000", cc.MasterPublicURL)
+	assert.Equal(t, []string{"ingress_hostname", "worker_pool_count"}, cc.ExtraFieldNames())
+
+	raw, ok := cc.ExtraField("worker_pool_count")
+	assert.True(t, ok)
+	assert.Equal(t, json.RawMessage("2"), raw)
+	hostname, ok := cc.ExtraString("ingress_hostname")
+	assert.True(t, ok)
+	assert.Equal(t, "cluster.us-south.containers.appdomain.cloud", hostname)
+	_, ok = cc.ExtraString("worker_pool_count")
+	assert.False(t, ok)
+	_, ok = cc.ExtraField("cluster_id")
+	assert.False(t, ok)
+
+	// Unknown fields survive a round trip.
+	data, err := json.Marshal(cc)
+	assert.Nil(t, err)
+	var roundTrip ClusterConfig
+	assert.Nil(t, json.Unmarshal(data, &roundTrip))
+	assert.Equal(t, cc, roundTrip)
+	assert.JSONEq(t, fullClusterConfig, string(data))
+}
+
+func TestClusterConfigAccessors(t *testing.T) {
+	testcases := []struct {
+		testcasename      string
+		clusterConfig     ClusterConfig
+		expectedRegion    string
+		expectedAccountID string
+	}{
+		{
+			testcasename:      "Region and account ID fields",
+			clusterConfig:     ClusterConfig{Region: "eu-de", AccountID: "account-1", CRN: "crn:v1:bluemix:public:containers-kubernetes:us-south:a/account-2:cluster-id::"},
+			expectedRegion:    "eu-de",
+			expectedAccountID: "account-1",
+		},
+		{
+			testcasename:      "Region and account ID from the CRN",
+			clusterConfig:     ClusterConfig{CRN: "crn:v1:bluemix:public:containers-kubernetes:jp-tok:a/account-2:cluster-id::"},
+			expectedRegion:    "jp-tok",
+			expectedAccountID: "account-2",
+		},
+		{
+			testcasename:   "Region from the master URL",
+			clusterConfig:  ClusterConfig{MasterURL: "https://c104.au-syd.containers.cloud.ibm.com:<port>"},
+			expectedRegion: "au-syd",
+		},
+		{
+			testcasename:      "Zone in the CRN and no master URL",
+			clusterConfig:     ClusterConfig{CRN: "crn:v1:bluemix:public:containers-kubernetes:dal10:a/account-3:cluster-id::"},
+			expectedAccountID: "account-3",
+		},
+		{
+			testcasename:  "Invalid CRN",
+			clusterConfig: ClusterConfig{CRN: "crn:v1:bluemix"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			assert.Equal(t, testcase.expectedRegion, testcase.clusterConfig.GetRegion())
+			assert.Equal(t, testcase.expectedAccountID, testcase.clusterConfig.GetAccountID())
+		})
+	}
+}
+
+func TestParseCRN(t *testing.T) {
+	crnString := "crn:v1:bluemix:public:containers-kubernetes:us-south:a/account-id:cluster-id::"
+	crn, err := ParseCRN(crnString)
+	assert.Nil(t, err)
+	assert.Equal(t, CRN{Version: "v1", CName: "bluemix", CType: "public", ServiceName: "containers-kubernetes",
+		Location: "us-south", Scope: "a/account-id", ServiceInstance: "cluster-id"}, crn)
+	assert.Equal(t, "account-id", crn.AccountID())
+	assert.Equal(t, crnString, crn.String())
+
+	for _, invalid := range []string{"", "crn:v1", "urn:v1:bluemix:public:containers-kubernetes:us-south:a/account-id:cluster-id::"} {
+		_, err = ParseCRN(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestGetClusterInfoExtendedFields(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Failed to get current working directory, error: %v", err)
+	}
+
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	if err = k8s_utils.FakeCreateCM(kc, filepath.Join(pwd, "..", "..", "test-fixtures/valid/vpc-gen2/prod/cluster-info.json")); err != nil {
+		t.Errorf("Failed to create cluster info config map, error: %v", err)
+	}
+
+	cc, err := GetClusterInfo(kc, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "account-id", cc.GetAccountID())
+	assert.Equal(t, "syd01", cc.Datacenter)
+	assert.Equal(t, "au-syd", cc.GetRegion())
+	assert.Equal(t, ClusterKindVPCGen2, cc.Kind())
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+)
+
+// ClusterKind is the kind of cluster the pod is running in, parsed from cluster-info.
+type ClusterKind string
+
+const (
+	// ClusterKindUnknown refers to clusters whose cluster-info does not match any known kind.
+	ClusterKindUnknown ClusterKind = "unknown"
+	// ClusterKindClassic refers to IKS/ROKS clusters on classic infrastructure (cluster type cruiser).
+	ClusterKindClassic ClusterKind = "classic"
+	// ClusterKindVPCGen2 refers to IKS/ROKS clusters on VPC Gen2 infrastructure (cluster type vpc-gen2_cruiser).
+	ClusterKindVPCGen2 ClusterKind = "vpc-gen2"
+	// ClusterKindSatellite refers to clusters in a Satellite location (cluster provider upi, or cluster type satellite_cruiser).
+	ClusterKindSatellite ClusterKind = "satellite"
+	// ClusterKindIPI refers to installer provisioned OpenShift clusters (cluster provider or type ipi).
+	ClusterKindIPI ClusterKind = "ipi"
+)
+
+// ParseClusterKind returns the kind of the cluster described by cc, the provider takes precedence over the type.
+func ParseClusterKind(cc ClusterConfig) ClusterKind {
+	switch {
+	case cc.ClusterProvider == utils.SatelliteProvider || cc.ClusterType == utils.SatelliteCruiser:
+		return ClusterKindSatellite
+	case cc.ClusterProvider == utils.IPIProvider || cc.ClusterType == utils.IPIProvider:
+		return ClusterKindIPI
+	case cc.ClusterType == utils.VPCGen2:
+		return ClusterKindVPCGen2
+	case cc.ClusterType == utils.Cruiser:
+		return ClusterKindClassic
+	}
+	return ClusterKindUnknown
+}
+
+// Kind returns the kind of the cluster, see ParseClusterKind.
+func (cc ClusterConfig) Kind() ClusterKind {
+	return ParseClusterKind(cc)
+}
+
+// IsVPC checks if the cluster is a managed cluster on VPC Gen2, such clusters reach IAM over the private network.
+func (kind ClusterKind) IsVPC() bool {
+	return kind == ClusterKindVPCGen2
+}
+
+// IsSatellite checks if the cluster is in a Satellite location.
+func (kind ClusterKind) IsSatellite() bool {
+	return kind == ClusterKindSatellite
+}
+
+// RequiresPublicIAM checks if the cluster cannot reach private IAM, and the public IAM is to be used when
+// no token exchange URL is provided. Satellite locations are outside the IBM Cloud private network.
+func (kind ClusterKind) RequiresPublicIAM() bool {
+	return kind == ClusterKindSatellite
+}
@@ -0,0 +1,138 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClusterKind(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testcases := []struct {
+		testcasename             string
+		clusterConfig            ClusterConfig
+		expectedKind             ClusterKind
+		isVPC                    bool
+		isSatellite              bool
+		expectedTokenExchangeURL string
+		isURLprovided            bool
+	}{
+		{
+			testcasename:             "Classic cluster",
+			clusterConfig:            ClusterConfig{ClusterType: utils.Cruiser, MasterURL: "https://c1.us-south.containers.cloud.ibm.com:<port>"},
+			expectedKind:             ClusterKindClassic,
+			expectedTokenExchangeURL: utils.ProdPrivateIAMURL + tokenExchangePath,
+		},
+		{
+			testcasename:             "VPC Gen2 cluster",
+			clusterConfig:            ClusterConfig{ClusterType: utils.VPCGen2, MasterURL: "https://c1.containers.test.cloud.ibm.com:<port>"},
+			expectedKind:             ClusterKindVPCGen2,
+			isVPC:                    true,
+			expectedTokenExchangeURL: utils.StagePrivateIAMURL + tokenExchangePath,
+		},
+		{
+			testcasename:             "Satellite cluster by provider",
+			clusterConfig:            ClusterConfig{ClusterProvider: utils.SatelliteProvider, ClusterType: utils.Cruiser},
+			expectedKind:             ClusterKindSatellite,
+			isSatellite:              true,
+			expectedTokenExchangeURL: utils.ProdPublicIAMURL + tokenExchangePath,
+			isURLprovided:            true,
+		},
+		{
+			testcasename:             "Satellite cluster by type",
+			clusterConfig:            ClusterConfig{ClusterType: utils.SatelliteCruiser, MasterURL: "https://c1.us-east.satellite.test.cloud.ibm.com:<port>"},
+			expectedKind:             ClusterKindSatellite,
+			isSatellite:              true,
+			expectedTokenExchangeURL: utils.StagePublicIAMURL + tokenExchangePath,
+			isURLprovided:            true,
+		},
+		{
+			testcasename:             "IPI cluster",
+			clusterConfig:            ClusterConfig{ClusterProvider: utils.IPIProvider},
+			expectedKind:             ClusterKindIPI,
+			expectedTokenExchangeURL: utils.ProdPrivateIAMURL + tokenExchangePath,
+		},
+		{
+			testcasename:             "Unknown cluster",
+			clusterConfig:            ClusterConfig{ClusterType: "kubernetes"},
+			expectedKind:             ClusterKindUnknown,
+			expectedTokenExchangeURL: utils.ProdPrivateIAMURL + tokenExchangePath,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			kind := ParseClusterKind(testcase.clusterConfig)
+			assert.Equal(t, testcase.expectedKind, kind)
+			assert.Equal(t, testcase.expectedKind, testcase.clusterConfig.Kind())
+			assert.Equal(t, testcase.isVPC, kind.IsVPC())
+			assert.Equal(t, testcase.isSatellite, kind.IsSatellite())
+			assert.Equal(t, testcase.isSatellite, kind.RequiresPublicIAM())
+			assert.Equal(t, testcase.isSatellite, IsSatellite(testcase.clusterConfig, logger))
+
+			url, isURLprovided := FrameTokenExchangeURLFromClusterInfo(testcase.clusterConfig, logger)
+			assert.Equal(t, testcase.expectedTokenExchangeURL, url)
+			assert.Equal(t, testcase.isURLprovided, isURLprovided)
+		})
+	}
+}
+
+func TestGetTokenExchangeURLfromStorageSecretStoreClusterKinds(t *testing.T) {
+	secretConfig := Config{
+		VPC:     &VPCProviderConfig{G2TokenExchangeURL: "https://iam.cloud.ibm.com"},
+		Bluemix: &BluemixConfig{IamURL: "https://iam.test.cloud.ibm.com"},
+	}
+
+	testcases := []struct {
+		testcasename  string
+		clusterConfig ClusterConfig
+		expectedURL   string
+		isURLprovided bool
+	}{
+		{
+			testcasename:  "VPC Gen2 cluster uses private IAM",
+			clusterConfig: ClusterConfig{ClusterType: utils.VPCGen2},
+			expectedURL:   utils.StagePrivateIAMURL + tokenExchangePath,
+		},
+		{
+			testcasename:  "Classic cluster uses the provided URL",
+			clusterConfig: ClusterConfig{ClusterType: utils.Cruiser},
+			expectedURL:   utils.StagePublicIAMURL,
+			isURLprovided: true,
+		},
+		{
+			testcasename:  "Satellite cluster of VPC type uses the provided URL",
+			clusterConfig: ClusterConfig{ClusterType: utils.VPCGen2, ClusterProvider: utils.SatelliteProvider},
+			expectedURL:   utils.StagePublicIAMURL,
+			isURLprovided: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			url, isURLprovided, err := GetTokenExchangeURLfromStorageSecretStore(testcase.clusterConfig, secretConfig, utils.Bluemix)
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedURL, url)
+			assert.Equal(t, testcase.isURLprovided, isURLprovided)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Source of a config value.
+type Source string
+
+const (
+	// SourceCloudConf is the cloud-conf config map.
+	SourceCloudConf Source = "cloud-conf"
+	// SourceClusterInfo is the cluster-info config map.
+	SourceClusterInfo Source = "cluster-info"
+	// SourceSecretStore is slclient.toml in storage-secret-store.
+	SourceSecretStore Source = "storage-secret-store"
+	// SourceDerived refers to values derived from the region and environment, see EndpointResolver.
+	SourceDerived Source = "derived"
+)
+
+// Value is a config value along with the source it was read from, Source is empty if the value was not found.
+type Value struct {
+	Value  string
+	Source Source
+}
+
+// DefaultConfigCacheTTL is how long the configs are cached when no TTL is provided.
+const DefaultConfigCacheTTL = 10 * time.Minute
+
+// CacheOptions ...
+type CacheOptions struct {
+	// TTL after which the configs are read again. Defaults to DefaultConfigCacheTTL,
+	// a negative value caches the configs until they are read again using readConfig.
+	TTL time.Duration
+
+	// SecretStoreSources are the slclient.toml sources tried in order, the first source that can be read is used.
+	// If empty, slclient.toml in storage-secret-store in the namespace of the k8s client is used.
+	SecretStoreSources []k8s_utils.SecretSource
+}
+
+// Cache holds the parsed cloud-conf, cluster-info and storage-secret-store of the cluster.
+// The configs are read on first use, and read again when readConfig is true or the TTL expires.
+// A config which does not exist is treated as absent, its values are taken from the other configs or derived.
+// If a config read before cannot be read again (for example the apiserver is unavailable), the last value read is kept.
+type Cache struct {
+	logger    *zap.Logger
+	k8sClient k8s_utils.KubernetesClient
+	opts      CacheOptions
+
+	mu           sync.Mutex
+	cloudConf    *CloudConf
+	clusterInfo  *ClusterConfig
+	secretConfig *Config
+	readAt       time.Time
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// NewCache returns a cache of the configs of the cluster, no config is read until a value is requested.
+func NewCache(logger *zap.Logger, k8sClient k8s_utils.KubernetesClient, optionalArgs ...CacheOptions) *Cache {
+	var opts CacheOptions
+	if len(optionalArgs) != 0 {
+		opts = optionalArgs[0]
+	}
+	if opts.TTL == 0 {
+		opts.TTL = DefaultConfigCacheTTL
+	}
+	return &Cache{logger: logger, k8sClient: k8sClient, opts: opts, now: time.Now}
+}
+
+// GetRIAASEndpoint returns the public VPC riaas endpoint.
+func (c *Cache) GetRIAASEndpoint(readConfig bool) (Value, error) {
+	return c.resolver(readConfig).riaasEndpoint(VisibilityPublic)
+}
+
+// GetPrivateRIAASEndpoint returns the private VPC riaas endpoint.
+func (c *Cache) GetPrivateRIAASEndpoint(readConfig bool) (Value, error) {
+	return c.resolver(readConfig).riaasEndpoint(VisibilityPrivate)
+}
+
+// GetContainerAPIRoute returns the public containers API endpoint.
+func (c *Cache) GetContainerAPIRoute(readConfig bool) (Value, error) {
+	return c.resolver(readConfig).containersAPIEndpoint(VisibilityPublic)
+}
+
+// GetPrivateContainerAPIRoute returns the private containers API endpoint.
+func (c *Cache) GetPrivateContainerAPIRoute(readConfig bool) (Value, error) {
+	return c.resolver(readConfig).containersAPIEndpoint(VisibilityPrivate)
+}
+
+// GetIAMEndpoint returns the IAM endpoint of the given visibility.
+func (c *Cache) GetIAMEndpoint(visibility Visibility, readConfig bool) Value {
+	return c.resolver(readConfig).iamEndpoint(visibility)
+}
+
+// GetResourceGroupID returns the resource group ID from cloud-conf, else from slclient.toml.
+func (c *Cache) GetResourceGroupID(readConfig bool) Value {
+	cloudConf, _, secretConfig := c.get(readConfig)
+	var values []Value
+	if cloudConf != nil {
+		values = append(values, Value{cloudConf.ResourceGroupID, SourceCloudConf})
+	}
+	if secretConfig != nil {
+		vpc := secretConfig.GetVPC()
+		values = append(values, Value{vpc.G2ResourceGroupID, SourceSecretStore}, Value{vpc.ResourceGroupID, SourceSecretStore})
+	}
+	return firstNonEmpty(values...)
+}
+
+// GetEnvironment returns the environment set in cloud-conf, else the one of the master URL in cluster-info.
+// Production is assumed when neither can be read.
+func (c *Cache) GetEnvironment(readConfig bool) (Environment, Source) {
+	cloudConf, clusterInfo, _ := c.get(readConfig)
+	return environment(cloudConf, clusterInfo)
+}
+
+// environment ...
+func environment(cloudConf *CloudConf, clusterInfo *ClusterConfig) (Environment, Source) {
+	if cloudConf != nil && cloudConf.Environment != "" {
+		return cloudConf.Environment, SourceCloudConf
+	}
+	if clusterInfo == nil {
+		return EnvironmentProduction, ""
+	}
+	if isProduction(clusterInfo.MasterURL) {
+		return EnvironmentProduction, SourceClusterInfo
+	}
+	return EnvironmentStage, SourceClusterInfo
+}
+
+// ReadAt returns the time at which the configs were last read, zero if they were never read.
+func (c *Cache) ReadAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readAt
+}
+
+// resolver returns an EndpointResolver using the cached configs.
+func (c *Cache) resolver(readConfig bool) EndpointResolver {
+	cloudConf, clusterInfo, secretConfig := c.get(readConfig)
+	env, source := environment(cloudConf, clusterInfo)
+	if source == "" {
+		// Neither cloud-conf nor cluster-info could be read, let the resolver detect the environment from the overrides.
+		env = ""
+	}
+	return EndpointResolver{Environment: env, CloudConf: cloudConf, Config: secretConfig}
+}
+
+// get returns the cached configs, reading them if readConfig is true, they were never read or the TTL expired.
+func (c *Cache) get(readConfig bool) (*CloudConf, *ClusterConfig, *Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expired := c.opts.TTL > 0 && c.now().Sub(c.readAt) >= c.opts.TTL
+	if readConfig || c.readAt.IsZero() || expired {
+		c.read()
+	}
+	return c.cloudConf, c.clusterInfo, c.secretConfig
+}
+
+// read reads all the configs. Configs which do not exist are set to nil, configs which cannot be read
+// keep the value read previously.
+func (c *Cache) read() {
+	c.logger.Info("Reading cluster configs", zap.Bool("first-read", c.readAt.IsZero()))
+
+	cloudConf, err := GetCloudConf(c.logger, c.k8sClient)
+	switch {
+	case err == nil:
+		c.cloudConf = &cloudConf
+	case c.cloudConf != nil && !isConfigRemoved(err):
+		c.logger.Warn("Unable to read cloud-conf, keeping the config read previously", zap.Error(err))
+	default:
+		c.cloudConf = nil
+	}
+
+	clusterInfo, err := GetClusterInfo(c.k8sClient, c.logger)
+	switch {
+	case err == nil:
+		c.clusterInfo = &clusterInfo
+	case c.clusterInfo != nil && !isConfigRemoved(err):
+		c.logger.Warn("Unable to read cluster-info, keeping the config read previously", zap.Error(err))
+	default:
+		c.clusterInfo = nil
+	}
+
+	secretConfig, removed, err := c.readSecretConfig()
+	switch {
+	case err == nil:
+		c.secretConfig = secretConfig
+	case c.secretConfig != nil && !removed:
+		c.logger.Warn("Unable to read slclient.toml, keeping the config read previously", zap.Error(err))
+	default:
+		c.secretConfig = nil
+	}
+
+	c.readAt = c.now()
+}
+
+// readSecretConfig returns slclient.toml from the first source which can be read and parsed.
+// removed is true if none of the sources exist.
+func (c *Cache) readSecretConfig() (secretConfig *Config, removed bool, err error) {
+	removed = true
+	for _, source := range (TokenExchangeURLOptions{SecretStoreSources: c.opts.SecretStoreSources}).secretStoreSources() {
+		var secret string
+		secret, err = k8s_utils.GetSecretDataFromSource(c.k8sClient, source)
+		if err == nil {
+			if secretConfig, err = ParseConfig(c.logger, secret); err == nil {
+				return secretConfig, false, nil
+			}
+		}
+		c.logger.Info("Unable to read slclient.toml, trying the next source", zap.String("namespace", source.Namespace),
+			zap.String("secret-name", source.SecretName), zap.String("key-name", source.Key), zap.Error(err))
+		removed = removed && isConfigRemoved(err)
+	}
+	return nil, removed, err
+}
+
+// isConfigRemoved checks if err means that the config does not exist, as opposed to a failure to read it.
+func isConfigRemoved(err error) bool {
+	return apierrors.IsNotFound(err) || utils.GetErrorCode(err) == utils.CredentialNotFound
+}
@@ -0,0 +1,245 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/k8s_utils"
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCacheSources(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	kc := newCacheTestClient(t, `{"region":"us-south","riaas_private_endpoint":"https://us-south.private.iaas.cloud.ibm.com"}`)
+	cache := NewCache(logger, kc)
+
+	value, err := cache.GetPrivateRIAASEndpoint(false)
+	assert.Nil(t, err)
+	assert.Equal(t, Value{"https://us-south.private.iaas.cloud.ibm.com", SourceCloudConf}, value)
+
+	value, err = cache.GetContainerAPIRoute(false)
+	assert.Nil(t, err)
+	assert.Equal(t, Value{"https://containers.test.cloud.ibm.com", SourceSecretStore}, value)
+
+	// Neither cloud-conf nor slclient.toml have the private route, it is derived from the region and the stage master URL.
+	value, err = cache.GetPrivateContainerAPIRoute(false)
+	assert.Nil(t, err)
+	assert.Equal(t, Value{"https://private.us-south.containers.test.cloud.ibm.com", SourceDerived}, value)
+
+	assert.Equal(t, Value{"https://iam.test.cloud.ibm.com", SourceSecretStore}, cache.GetIAMEndpoint(VisibilityPublic, false))
+	assert.Equal(t, Value{}, cache.GetResourceGroupID(false))
+
+	env, source := cache.GetEnvironment(false)
+	assert.Equal(t, EnvironmentStage, env)
+	assert.Equal(t, SourceClusterInfo, source)
+}
+
+func TestCacheVPCFixture(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	kc := newCacheTestClientFromFixtures(t, "test-fixtures/valid/vpc-gen2/prod", `{"region":"au-syd"}`)
+	now := time.Now()
+	cache := NewCache(logger, kc)
+	cache.now = func() time.Time { return now }
+
+	// iks_token_exchange_endpoint_private_url holds a containers URL, the private IAM endpoint is derived.
+	assert.Equal(t, Value{utils.ProdPrivateIAMURL, SourceDerived}, cache.GetIAMEndpoint(VisibilityPrivate, false))
+	assert.Equal(t, Value{"https://iam.bluemix.net", SourceSecretStore}, cache.GetIAMEndpoint(VisibilityPublic, false))
+
+	value, err := cache.GetPrivateRIAASEndpoint(false)
+	assert.Nil(t, err)
+	assert.Equal(t, Value{"https://au-syd.private.iaas.cloud.ibm.com", SourceSecretStore}, value)
+	value, err = cache.GetPrivateContainerAPIRoute(false)
+	assert.Nil(t, err)
+	assert.Equal(t, Value{"https://private.au-syd.containers.cloud.ibm.com", SourceSecretStore}, value)
+	assert.Equal(t, Value{"<resouce-id>", SourceSecretStore}, cache.GetResourceGroupID(false))
+
+	// Same values from cache and once the TTL expired
+	assert.Equal(t, Value{utils.ProdPrivateIAMURL, SourceDerived}, cache.GetIAMEndpoint(VisibilityPrivate, false))
+	now = now.Add(2 * DefaultConfigCacheTTL)
+	assert.Equal(t, Value{utils.ProdPrivateIAMURL, SourceDerived}, cache.GetIAMEndpoint(VisibilityPrivate, false))
+}
+
+func TestCacheEnvironmentOverride(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	kc := newCacheTestClient(t, `{"region":"us-south","environment":"prod"}`)
+	cache := NewCache(logger, kc)
+
+	env, source := cache.GetEnvironment(false)
+	assert.Equal(t, EnvironmentProduction, env)
+	assert.Equal(t, SourceCloudConf, source)
+
+	value, err := cache.GetPrivateContainerAPIRoute(false)
+	assert.Nil(t, err)
+	assert.Equal(t, Value{"https://private.us-south.containers.cloud.ibm.com", SourceDerived}, value)
+}
+
+func TestCacheReadConfig(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	kc := newCacheTestClient(t, `{"region":"us-south","resource_group_id":"rg-1"}`)
+	now := time.Now()
+	cache := NewCache(logger, kc, CacheOptions{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+
+	assert.Equal(t, Value{"rg-1", SourceCloudConf}, cache.GetResourceGroupID(false))
+	readAt := cache.ReadAt()
+	assert.Equal(t, now, readAt)
+
+	updateCloudConf(t, kc, `{"region":"us-south","resource_group_id":"rg-2"}`)
+
+	// Cached value is returned until readConfig is true or the TTL expires.
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, Value{"rg-1", SourceCloudConf}, cache.GetResourceGroupID(false))
+	assert.Equal(t, readAt, cache.ReadAt())
+
+	assert.Equal(t, Value{"rg-2", SourceCloudConf}, cache.GetResourceGroupID(true))
+	assert.Equal(t, now, cache.ReadAt())
+
+	updateCloudConf(t, kc, `{"region":"us-south","resource_group_id":"rg-3"}`)
+	now = now.Add(time.Minute)
+	assert.Equal(t, Value{"rg-3", SourceCloudConf}, cache.GetResourceGroupID(false))
+
+	// cloud-conf deleted, slclient.toml has neither the resource group nor the region to derive endpoints from.
+	if err := kc.Clientset.CoreV1().ConfigMaps(kc.Namespace).Delete(context.TODO(), cloudConfCM, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete cloud-conf, error: %v", err)
+	}
+	assert.Equal(t, Value{}, cache.GetResourceGroupID(true))
+	_, err := cache.GetRIAASEndpoint(false)
+	assert.Equal(t, utils.ConfigParse, utils.GetErrorCode(err))
+}
+
+func TestCacheKeepsConfigsOnReadFailure(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	kc := newCacheTestClient(t, `{"region":"us-south","resource_group_id":"rg-1"}`)
+	now := time.Now()
+	cache := NewCache(logger, kc, CacheOptions{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+
+	assert.Equal(t, Value{"rg-1", SourceCloudConf}, cache.GetResourceGroupID(false))
+	assert.Equal(t, Value{"https://containers.test.cloud.ibm.com", SourceSecretStore}, valueOf(cache.GetContainerAPIRoute(false)))
+	env, source := cache.GetEnvironment(false)
+	assert.Equal(t, EnvironmentStage, env)
+	assert.Equal(t, SourceClusterInfo, source)
+
+	// The apiserver fails every read once the TTL expired, the configs read before are kept.
+	kc.Clientset.(*fake.Clientset).PrependReactor("get", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewServiceUnavailable("apiserver unavailable")
+	})
+	now = now.Add(time.Minute)
+	assert.Equal(t, Value{"rg-1", SourceCloudConf}, cache.GetResourceGroupID(false))
+	assert.Equal(t, now, cache.ReadAt())
+	assert.Equal(t, Value{"https://containers.test.cloud.ibm.com", SourceSecretStore}, valueOf(cache.GetContainerAPIRoute(true)))
+	env, source = cache.GetEnvironment(true)
+	assert.Equal(t, EnvironmentStage, env)
+	assert.Equal(t, SourceClusterInfo, source)
+}
+
+func TestCacheSecretStoreSources(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	// The first source cannot be parsed, slclient.toml is read from the next one.
+	kc := newCacheTestClient(t, `{}`)
+	if err = k8s_utils.FakeCreateSecretWithKey(kc, "invalid-secret-store", utils.SECRET_STORE_FILE, filepath.Join(pwd, "..", "..", "test-fixtures/invalid/slclient.toml")); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+	cache := NewCache(logger, kc, CacheOptions{SecretStoreSources: []k8s_utils.SecretSource{
+		{SecretName: "invalid-secret-store", Key: utils.SECRET_STORE_FILE},
+		{SecretName: utils.STORAGE_SECRET_STORE_SECRET, Key: utils.SECRET_STORE_FILE},
+	}})
+	assert.Equal(t, Value{"https://containers.test.cloud.ibm.com", SourceSecretStore}, valueOf(cache.GetContainerAPIRoute(false)))
+}
+
+// valueOf returns the value, ignoring the error.
+func valueOf(value Value, _ error) Value {
+	return value
+}
+
+func TestCacheWithoutTTL(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	kc := newCacheTestClient(t, `{"region":"us-south","resource_group_id":"rg-1"}`)
+	now := time.Now()
+	cache := NewCache(logger, kc, CacheOptions{TTL: -1})
+	cache.now = func() time.Time { return now }
+
+	assert.Equal(t, Value{"rg-1", SourceCloudConf}, cache.GetResourceGroupID(false))
+	updateCloudConf(t, kc, `{"region":"us-south","resource_group_id":"rg-2"}`)
+	now = now.Add(24 * time.Hour)
+	assert.Equal(t, Value{"rg-1", SourceCloudConf}, cache.GetResourceGroupID(false))
+}
+
+// newCacheTestClient returns a client with the classic stage slclient.toml and cluster-info, and the given cloud-conf.
+func newCacheTestClient(t *testing.T, cloudConf string) k8s_utils.KubernetesClient {
+	return newCacheTestClientFromFixtures(t, "test-fixtures/valid/classic/stage", cloudConf)
+}
+
+// newCacheTestClientFromFixtures returns a client with the slclient.toml and cluster-info in fixturesDir, and the given cloud-conf.
+func newCacheTestClientFromFixtures(t *testing.T, fixturesDir, cloudConf string) k8s_utils.KubernetesClient {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory, error: %v", err)
+	}
+
+	kc, _ := k8s_utils.FakeGetk8sClientSet()
+	if err = k8s_utils.FakeCreateSecret(kc, utils.DEFAULT, filepath.Join(pwd, "..", "..", fixturesDir, "slclient.toml")); err != nil {
+		t.Fatalf("Failed to create secret, error: %v", err)
+	}
+	if err = k8s_utils.FakeCreateCM(kc, filepath.Join(pwd, "..", "..", fixturesDir, "cluster-info.json")); err != nil {
+		t.Fatalf("Failed to create cluster info config map, error: %v", err)
+	}
+	createCloudConf(t, kc, cloudConf)
+	return kc
+}
+
+// updateCloudConf ...
+func updateCloudConf(t *testing.T, kc k8s_utils.KubernetesClient, data string) {
+	cm, err := kc.Clientset.CoreV1().ConfigMaps(kc.Namespace).Get(context.TODO(), cloudConfCM, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get cloud-conf, error: %v", err)
+	}
+	cm.Data[cloudConfData] = data
+	if _, err = kc.Clientset.CoreV1().ConfigMaps(kc.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update cloud-conf, error: %v", err)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -121,7 +122,7 @@ func TestFrameTokenExchangeURL(t *testing.T) {
 			testCaseName:     "Classic cluster prod",
 			secretDataPath:   "test-fixtures/valid/classic/prod/slclient.toml",
 			clusterInfoPath:  "test-fixtures/valid/classic/prod/cluster-info.json",
-			expectedTokenURL: "https://iam.cloud.ibm.com/identity/token",
+			expectedTokenURL: "https://iam.cloud.ibm.com",
 			isURLprovided:    true,
 			providerToBeUsed: utils.Bluemix,
 		},
@@ -129,7 +130,7 @@ func TestFrameTokenExchangeURL(t *testing.T) {
 			testCaseName:     "Classic cluster stage",
 			secretDataPath:   "test-fixtures/valid/classic/stage/slclient.toml",
 			clusterInfoPath:  "test-fixtures/valid/classic/stage/cluster-info.json",
-			expectedTokenURL: "https://iam.test.cloud.ibm.com/identity/token",
+			expectedTokenURL: "https://iam.test.cloud.ibm.com",
 			isURLprovided:    true,
 			providerToBeUsed: utils.Bluemix,
 		},
@@ -137,7 +138,7 @@ func TestFrameTokenExchangeURL(t *testing.T) {
 			testCaseName:     "Satellite cluster prod",
 			secretDataPath:   "test-fixtures/valid/vpc-gen2/prod/satellite/slclient.toml",
 			clusterInfoPath:  "test-fixtures/valid/vpc-gen2/prod/satellite/cluster-info.json",
-			expectedTokenURL: "https://iam.cloud.ibm.com/identity/token",
+			expectedTokenURL: "https://iam.cloud.ibm.com",
 			isURLprovided:    true,
 			providerToBeUsed: utils.VPC,
 		},
@@ -169,6 +170,37 @@ func TestFrameTokenExchangeURL(t *testing.T) {
 	}
 }
 
+func TestFrameTokenExchangeURLWithSecretStoreSources(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Failed to get current working directory, error: %v", err)
+	}
+
+	k8sClient, _ := k8s_utils.FakeGetk8sClientSet()
+	tenantClient := k8s_utils.KubernetesClient{Namespace: "tenant-a", Clientset: k8sClient.Clientset}
+	secretfilePath := filepath.Join(pwd, "..", "..", "test-fixtures/valid/classic/stage/slclient.toml")
+	if err = k8s_utils.FakeCreateSecretWithKey(tenantClient, "tenant-secret-store", "tenant.toml", secretfilePath); err != nil {
+		t.Errorf("Failed to create secret, error: %v", err)
+	}
+	clusterInfoPath := filepath.Join(pwd, "..", "..", "test-fixtures/valid/classic/stage/cluster-info.json")
+	if err = k8s_utils.FakeCreateCM(k8sClient, clusterInfoPath); err != nil {
+		t.Errorf("Failed to create cluster info config map, error: %v", err)
+	}
+
+	opts := TokenExchangeURLOptions{
+		SecretStoreSources: []k8s_utils.SecretSource{
+			{Namespace: "tenant-b", SecretName: "tenant-secret-store", Key: "tenant.toml"},
+			{Namespace: "tenant-a", SecretName: "tenant-secret-store", Key: "tenant.toml"},
+		},
+	}
+	returnedURL, provided := FrameTokenExchangeURL(k8sClient, utils.Bluemix, logger, opts)
+	assert.Equal(t, "https://iam.test.cloud.ibm.com", returnedURL)
+	assert.True(t, provided)
+}
+
 func GetTestLogger(t *testing.T) (logger *zap.Logger, teardown func()) {
 	atom := zap.NewAtomicLevel()
 	atom.SetLevel(zap.DebugLevel)
@@ -196,3 +228,406 @@ func GetTestLogger(t *testing.T) (logger *zap.Logger, teardown func()) {
 	}
 	return
 }
+
+func TestResolveTokenExchangeURL(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Failed to get current working directory, error: %v", err)
+	}
+
+	testcases := []struct {
+		testCaseName    string
+		secretDataPath  string
+		clusterInfoPath string
+		cloudConf       string
+		expectedURL     string
+		expectedRule    string
+		expectedSources []Source
+	}{
+		{
+			testCaseName:    "Token exchange URL in cloud-conf",
+			cloudConf:       `{"token_exchange_url":"https://private.iam.cloud.ibm.com"}`,
+			expectedURL:     "https://private.iam.cloud.ibm.com/identity/token",
+			expectedRule:    RuleCloudConf,
+			expectedSources: []Source{SourceCloudConf},
+		},
+		{
+			testCaseName:    "No cluster-info",
+			expectedURL:     "https://private.iam.cloud.ibm.com/identity/token",
+			expectedRule:    RuleClusterInfoUnavailable,
+			expectedSources: []Source{SourceCloudConf, SourceClusterInfo},
+		},
+		{
+			testCaseName:    "VPC gen2 stage cluster",
+			secretDataPath:  "test-fixtures/valid/vpc-gen2/stage/slclient.toml",
+			clusterInfoPath: "test-fixtures/valid/vpc-gen2/stage/cluster-info.json",
+			expectedURL:     "https://private.iam.test.cloud.ibm.com/identity/token",
+			expectedRule:    RuleVPCStagePrivateIAM,
+			expectedSources: []Source{SourceCloudConf, SourceClusterInfo, SourceSecretStore},
+		},
+		{
+			testCaseName:    "VPC gen2 stage cluster with environment overridden in cloud-conf",
+			secretDataPath:  "test-fixtures/valid/vpc-gen2/stage/slclient.toml",
+			clusterInfoPath: "test-fixtures/valid/vpc-gen2/stage/cluster-info.json",
+			cloudConf:       `{"environment":"prod"}`,
+			expectedURL:     "https://private.iam.cloud.ibm.com/identity/token",
+			expectedRule:    RuleVPCProdPrivateIAM,
+			expectedSources: []Source{SourceCloudConf, SourceCloudConf, SourceClusterInfo, SourceSecretStore},
+		},
+		{
+			testCaseName:    "Satellite cluster with environment overridden in cloud-conf",
+			clusterInfoPath: "test-fixtures/valid/vpc-gen2/prod/satellite/cluster-info.json",
+			cloudConf:       `{"environment":"stage"}`,
+			expectedURL:     "https://iam.test.cloud.ibm.com/identity/token",
+			expectedRule:    RuleSatelliteStagePublicIAM,
+			expectedSources: []Source{SourceCloudConf, SourceCloudConf, SourceClusterInfo, SourceSecretStore},
+		},
+		{
+			testCaseName:    "Classic cluster prod",
+			secretDataPath:  "test-fixtures/valid/classic/prod/slclient.toml",
+			clusterInfoPath: "test-fixtures/valid/classic/prod/cluster-info.json",
+			expectedURL:     "https://iam.cloud.ibm.com",
+			expectedRule:    RuleProviderURLProvided,
+			expectedSources: []Source{SourceCloudConf, SourceClusterInfo, SourceSecretStore},
+		},
+		{
+			testCaseName:    "Satellite cluster without storage-secret-store",
+			clusterInfoPath: "test-fixtures/valid/vpc-gen2/prod/satellite/cluster-info.json",
+			expectedURL:     "https://iam.cloud.ibm.com/identity/token",
+			expectedRule:    RuleSatelliteProdPublicIAM,
+			expectedSources: []Source{SourceCloudConf, SourceClusterInfo, SourceSecretStore},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			k8sClient, _ := k8s_utils.FakeGetk8sClientSet()
+			if testcase.secretDataPath != "" {
+				if err = k8s_utils.FakeCreateSecret(k8sClient, utils.DEFAULT, filepath.Join(pwd, "..", "..", testcase.secretDataPath)); err != nil {
+					t.Errorf("Failed to create secret, error: %v", err)
+				}
+			}
+			if testcase.clusterInfoPath != "" {
+				if err = k8s_utils.FakeCreateCM(k8sClient, filepath.Join(pwd, "..", "..", testcase.clusterInfoPath)); err != nil {
+					t.Errorf("Failed to create cluster info config map, error: %v", err)
+				}
+			}
+			if testcase.cloudConf != "" {
+				createCloudConf(t, k8sClient, testcase.cloudConf)
+			}
+
+			report := ResolveTokenExchangeURL(k8sClient, utils.Bluemix, logger)
+			assert.Equal(t, testcase.expectedURL, report.URL)
+			assert.Equal(t, testcase.expectedRule, report.Rule)
+			var sources []Source
+			for _, step := range report.Steps {
+				sources = append(sources, step.Source)
+			}
+			assert.Equal(t, testcase.expectedSources, sources)
+			assert.Contains(t, report.String(), testcase.expectedRule)
+
+			url, isURLprovided := FrameTokenExchangeURL(k8sClient, utils.Bluemix, logger)
+			assert.Equal(t, report.URL, url)
+			assert.Equal(t, report.IsURLProvided, isURLprovided)
+		})
+	}
+}
+
+func TestGetTokenExchangeURLfromStorageSecretStoreProviders(t *testing.T) {
+	// Each provider has a token exchange URL of a different environment and visibility.
+	secretConfig := Config{
+		VPC:       &VPCProviderConfig{G2TokenExchangeURL: "https://private.iam.cloud.ibm.com"},
+		Bluemix:   &BluemixConfig{IamURL: "https://iam.test.cloud.ibm.com"},
+		Softlayer: &SoftlayerConfig{SoftlayerTokenExchangeURL: "https://iam.cloud.ibm.com/identity/token"},
+	}
+
+	clusters := map[ClusterKind]ClusterConfig{
+		ClusterKindClassic:   {ClusterType: utils.Cruiser},
+		ClusterKindVPCGen2:   {ClusterType: utils.VPCGen2},
+		ClusterKindSatellite: {ClusterType: utils.SatelliteCruiser, ClusterProvider: utils.SatelliteProvider},
+		ClusterKindIPI:       {ClusterProvider: utils.IPIProvider},
+		ClusterKindUnknown:   {},
+	}
+
+	type providerTestcase struct {
+		testcasename  string
+		clusterKind   ClusterKind
+		providerType  string
+		config        Config
+		expectedURL   string
+		isURLprovided bool
+		expectError   bool
+	}
+
+	testcases := []providerTestcase{
+		{testcasename: "VPC cluster, vpc provider with private URL", clusterKind: ClusterKindVPCGen2, providerType: utils.VPC, config: secretConfig,
+			expectedURL: "https://private.iam.cloud.ibm.com/identity/token", isURLprovided: true},
+		{testcasename: "VPC cluster, bluemix provider with public stage URL", clusterKind: ClusterKindVPCGen2, providerType: utils.Bluemix, config: secretConfig,
+			expectedURL: utils.StagePrivateIAMURL + tokenExchangePath},
+		{testcasename: "VPC cluster, softlayer provider with public prod URL", clusterKind: ClusterKindVPCGen2, providerType: utils.Softlayer, config: secretConfig,
+			expectedURL: utils.ProdPrivateIAMURL + tokenExchangePath},
+		{testcasename: "VPC cluster, missing VPC section", clusterKind: ClusterKindVPCGen2, providerType: utils.VPC, config: Config{},
+			expectedURL: utils.ProdPrivateIAMURL + tokenExchangePath},
+		{testcasename: "VPC cluster, missing Bluemix section", clusterKind: ClusterKindVPCGen2, providerType: utils.Bluemix, config: Config{VPC: secretConfig.VPC},
+			expectedURL: utils.ProdPrivateIAMURL + tokenExchangePath},
+		{testcasename: "VPC cluster, unknown provider", clusterKind: ClusterKindVPCGen2, providerType: "aws", config: secretConfig, expectError: true},
+	}
+	for _, kind := range []ClusterKind{ClusterKindClassic, ClusterKindSatellite, ClusterKindIPI, ClusterKindUnknown} {
+		testcases = append(testcases, []providerTestcase{
+			{testcasename: string(kind) + " cluster, vpc provider", clusterKind: kind, providerType: utils.VPC, config: secretConfig,
+				expectedURL: "https://private.iam.cloud.ibm.com", isURLprovided: true},
+			{testcasename: string(kind) + " cluster, bluemix provider", clusterKind: kind, providerType: utils.Bluemix, config: secretConfig,
+				expectedURL: "https://iam.test.cloud.ibm.com", isURLprovided: true},
+			{testcasename: string(kind) + " cluster, softlayer provider", clusterKind: kind, providerType: utils.Softlayer, config: secretConfig,
+				expectedURL: "https://iam.cloud.ibm.com/identity/token", isURLprovided: true},
+			{testcasename: string(kind) + " cluster, missing sections", clusterKind: kind, providerType: utils.Softlayer, config: Config{}, isURLprovided: true, expectError: true},
+			{testcasename: string(kind) + " cluster, unknown provider", clusterKind: kind, providerType: "aws", config: secretConfig, expectError: true},
+		}...)
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			url, isURLprovided, err := GetTokenExchangeURLfromStorageSecretStore(clusters[testcase.clusterKind], testcase.config, testcase.providerType)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expectedURL, url)
+			assert.Equal(t, testcase.isURLprovided, isURLprovided)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testcases := []struct {
+		testcasename     string
+		secretconfigpath string
+		providerType     string
+		expectedField    string
+	}{
+		{testcasename: "Valid config for vpc", secretconfigpath: "test-fixtures/valid/slclient.toml", providerType: utils.VPC},
+		{testcasename: "Valid config for bluemix", secretconfigpath: "test-fixtures/valid/slclient.toml", providerType: utils.Bluemix},
+		{testcasename: "Valid config for softlayer", secretconfigpath: "test-fixtures/invalid/missing_vpc_section.toml", providerType: utils.Softlayer},
+		{testcasename: "Config without Softlayer section", secretconfigpath: "test-fixtures/valid/slclient.toml", providerType: utils.Softlayer, expectedField: "[Softlayer]"},
+		{testcasename: "Missing VPC section", secretconfigpath: "test-fixtures/invalid/missing_vpc_section.toml", providerType: utils.VPC, expectedField: "[VPC]"},
+		{testcasename: "Missing VPC section, bluemix provider", secretconfigpath: "test-fixtures/invalid/missing_vpc_section.toml", providerType: utils.Bluemix},
+		{testcasename: "Missing Bluemix section", secretconfigpath: "test-fixtures/invalid/missing_bluemix_section.toml", providerType: utils.Bluemix, expectedField: "[Bluemix]"},
+		{testcasename: "Missing Softlayer section", secretconfigpath: "test-fixtures/invalid/missing_softlayer_section.toml", providerType: utils.Softlayer, expectedField: "[Softlayer]"},
+		{testcasename: "Empty VPC api key", secretconfigpath: "test-fixtures/invalid/empty_apikey.toml", providerType: utils.VPC, expectedField: "VPC.g2_api_key"},
+		{testcasename: "Empty Bluemix api key", secretconfigpath: "test-fixtures/invalid/empty_apikey.toml", providerType: utils.Bluemix, expectedField: "Bluemix.iam_api_key"},
+		{testcasename: "Unknown provider", secretconfigpath: "test-fixtures/valid/slclient.toml", providerType: "aws", expectedField: "provider"},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			pwd, err := os.Getwd()
+			if err != nil {
+				t.Errorf("Failed to get current working directory, error: %v", err)
+			}
+
+			byteData, err := ioutil.ReadFile(filepath.Join(pwd, "..", "..", testcase.secretconfigpath))
+			if err != nil {
+				t.Errorf("Failed to read %s, error: %v", testcase.secretconfigpath, err)
+			}
+
+			conf, err := ParseConfig(logger, string(byteData))
+			assert.Nil(t, err)
+
+			err = conf.Validate(testcase.providerType)
+			if testcase.expectedField == "" {
+				assert.Nil(t, err)
+				assert.NotEmpty(t, conf.APIKey(testcase.providerType))
+				return
+			}
+			var validationErr ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, testcase.expectedField, validationErr.Errors[0].Field)
+		})
+	}
+}
+
+func TestConfigAccessorsOnMissingSections(t *testing.T) {
+	var nilConfig *Config
+	for _, conf := range []*Config{nilConfig, {}} {
+		assert.Equal(t, VPCProviderConfig{}, conf.GetVPC())
+		assert.Equal(t, BluemixConfig{}, conf.GetBluemix())
+		assert.Equal(t, SoftlayerConfig{}, conf.GetSoftlayer())
+		assert.Equal(t, IKSConfig{}, conf.GetIKS())
+		assert.Equal(t, APIConfig{}, conf.GetAPI())
+		for _, providerType := range []string{utils.VPC, utils.Bluemix, utils.Softlayer} {
+			assert.Empty(t, conf.APIKey(providerType))
+			assert.Empty(t, conf.TokenExchangeURL(providerType))
+			assert.False(t, conf.Encryption(providerType))
+			assert.NotNil(t, conf.Validate(providerType))
+		}
+	}
+
+	conf := &Config{VPC: &VPCProviderConfig{G2APIKey: "key", G2TokenExchangeURL: "https://iam.cloud.ibm.com", Encryption: true}}
+	assert.Equal(t, "key", conf.APIKey(utils.VPC))
+	assert.Equal(t, "https://iam.cloud.ibm.com", conf.TokenExchangeURL(utils.VPC))
+	assert.True(t, conf.Encryption(utils.VPC))
+	assert.Nil(t, conf.Validate(utils.VPC))
+}
+
+func TestParseConfigUndecodedKeys(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testcases := []struct {
+		testcasename     string
+		secretconfigpath string
+		data             string
+		expectedKeys     []string
+	}{
+		{
+			testcasename:     "Sample secret store",
+			secretconfigpath: "secrets/storage-secret-store/slclient.toml",
+			expectedKeys:     []string{"Bluemix.pay_tier", "Softlayer.encryption"},
+		},
+		{
+			testcasename:     "Misspelled api key",
+			secretconfigpath: "test-fixtures/invalid/misspelled_key.toml",
+			expectedKeys:     []string{"Bluemix.pay_tier", "VPC.g2_apikey"},
+		},
+		{
+			testcasename: "No unknown keys",
+			data:         "[VPC]\n  g2_api_key = \"vpc-api-key\"\n  encryption = true\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			pwd, err := os.Getwd()
+			if err != nil {
+				t.Errorf("Failed to get current working directory, error: %v", err)
+			}
+
+			data := testcase.data
+			if testcase.secretconfigpath != "" {
+				byteData, err := ioutil.ReadFile(filepath.Join(pwd, "..", "..", testcase.secretconfigpath))
+				if err != nil {
+					t.Errorf("Failed to read %s, error: %v", testcase.secretconfigpath, err)
+				}
+				data = string(byteData)
+			}
+
+			conf, err := ParseConfig(logger, data)
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedKeys, conf.UndecodedKeys())
+
+			conf, err = ParseConfigWithOptions(logger, data, ParseOptions{Strict: true})
+			if len(testcase.expectedKeys) == 0 {
+				assert.Nil(t, err)
+				assert.NotNil(t, conf)
+				return
+			}
+			assert.Nil(t, conf)
+			assert.True(t, errors.Is(err, utils.ErrConfigParse))
+			var validationErr ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			for i, key := range testcase.expectedKeys {
+				assert.Equal(t, FieldError{Field: key, Reason: "unknown key"}, validationErr.Errors[i])
+			}
+			assert.NotContains(t, err.Error(), "kJV2a1RsVCPmN")
+		})
+	}
+}
+
+func TestParseConfigUnknownKeysWarnedOnce(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core)
+
+	data := "[VPC]\n  g2_apikey = \"vpc-api-key\"\n  unknown_key_warned_once = true\n"
+	for i := 0; i < 3; i++ {
+		conf, err := ParseConfig(logger, data)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"VPC.g2_apikey", "VPC.unknown_key_warned_once"}, conf.UndecodedKeys())
+	}
+	assert.Equal(t, 1, logs.FilterMessage("Ignoring unknown keys in config").FilterLevelExact(zap.WarnLevel).Len())
+	assert.Equal(t, 2, logs.FilterMessage("Ignoring unknown keys in config").FilterLevelExact(zap.DebugLevel).Len())
+
+	// A new content of the secret is warned about again
+	_, err := ParseConfig(logger, data+"  encryption = true\n")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, logs.FilterMessage("Ignoring unknown keys in config").FilterLevelExact(zap.WarnLevel).Len())
+}
+
+func TestValidateSecretStore(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testcases := []struct {
+		testcasename     string
+		secretconfigpath string
+		data             string
+		providers        []string
+		expectedOK       bool
+		expectedStrictOK bool
+		expectedOutput   []string
+	}{
+		{
+			testcasename:     "Sample secret store",
+			secretconfigpath: "secrets/storage-secret-store/slclient.toml",
+			expectedOK:       true,
+			expectedOutput:   []string{"WARN  Bluemix.pay_tier: unknown key, ignored", "WARN  Softlayer.encryption: unknown key, ignored", "OK    vpc", "OK    bluemix", "OK    softlayer"},
+		},
+		{
+			testcasename:     "Misspelled api key",
+			secretconfigpath: "test-fixtures/invalid/misspelled_key.toml",
+			providers:        []string{utils.VPC},
+			expectedOutput:   []string{"WARN  VPC.g2_apikey: unknown key, ignored", "ERROR vpc: VPC.g2_api_key: is required"},
+		},
+		{
+			testcasename:     "Missing section of the requested provider",
+			secretconfigpath: "test-fixtures/invalid/missing_vpc_section.toml",
+			providers:        []string{utils.VPC},
+			expectedOutput:   []string{"ERROR vpc: [VPC]: section is missing"},
+		},
+		{
+			testcasename:     "No unknown keys",
+			data:             "[VPC]\n  g2_api_key = \"vpc-api-key\"\n  encryption = true\n",
+			expectedOK:       true,
+			expectedStrictOK: true,
+			expectedOutput:   []string{"OK    vpc"},
+		},
+		{
+			testcasename:   "No provider section",
+			data:           "[API]\n  PassthroughSecret = \"\"\n",
+			expectedOutput: []string{"ERROR provider: none of the [VPC], [Bluemix] or [Softlayer] sections is present"},
+		},
+		{
+			testcasename:   "Invalid toml",
+			data:           "[VPC]\n  g2_api_key = secret-value\n",
+			expectedOutput: []string{"ERROR parse: "},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			data := testcase.data
+			if testcase.secretconfigpath != "" {
+				pwd, err := os.Getwd()
+				if err != nil {
+					t.Errorf("Failed to get current working directory, error: %v", err)
+				}
+				byteData, err := ioutil.ReadFile(filepath.Join(pwd, "..", "..", testcase.secretconfigpath))
+				if err != nil {
+					t.Errorf("Failed to read %s, error: %v", testcase.secretconfigpath, err)
+				}
+				data = string(byteData)
+			}
+
+			report := ValidateSecretStore(logger, data, testcase.providers...)
+			assert.Equal(t, testcase.expectedOK, report.OK(false))
+			assert.Equal(t, testcase.expectedStrictOK, report.OK(true))
+			for _, line := range testcase.expectedOutput {
+				assert.Contains(t, report.String(), line)
+			}
+			assert.NotContains(t, report.String(), "secret-value")
+			assert.NotContains(t, report.String(), "api-key\"")
+		})
+	}
+}
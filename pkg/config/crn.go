@@ -0,0 +1,73 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+)
+
+// crnSegments is the number of segments of a CRN, crn:version:cname:ctype:service-name:location:scope:service-instance:resource-type:resource
+const crnSegments = 10
+
+// CRN is an IBM Cloud resource name, for example crn:v1:bluemix:public:containers-kubernetes:us-south:a/<account-id>:<cluster-id>::
+type CRN struct {
+	Version         string
+	CName           string
+	CType           string
+	ServiceName     string
+	Location        string
+	Scope           string
+	ServiceInstance string
+	ResourceType    string
+	Resource        string
+}
+
+// ParseCRN parses a CRN.
+func ParseCRN(crn string) (CRN, error) {
+	segments := strings.Split(crn, ":")
+	if len(segments) != crnSegments || segments[0] != "crn" {
+		return CRN{}, utils.Error{Description: fmt.Sprintf(utils.ErrInvalidCRN, crn), Code: utils.ConfigParse}
+	}
+	return CRN{
+		Version:         segments[1],
+		CName:           segments[2],
+		CType:           segments[3],
+		ServiceName:     segments[4],
+		Location:        segments[5],
+		Scope:           segments[6],
+		ServiceInstance: segments[7],
+		ResourceType:    segments[8],
+		Resource:        segments[9],
+	}, nil
+}
+
+// AccountID returns the account ID of an account scoped CRN (scope a/<account-id>), empty otherwise.
+func (crn CRN) AccountID() string {
+	if strings.HasPrefix(crn.Scope, "a/") {
+		return strings.TrimPrefix(crn.Scope, "a/")
+	}
+	return ""
+}
+
+// String formats the CRN.
+func (crn CRN) String() string {
+	return strings.Join([]string{"crn", crn.Version, crn.CName, crn.CType, crn.ServiceName, crn.Location, crn.Scope,
+		crn.ServiceInstance, crn.ResourceType, crn.Resource}, ":")
+}
@@ -0,0 +1,295 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+)
+
+// Visibility of an endpoint, private endpoints are reachable only over the IBM Cloud private network.
+type Visibility string
+
+const (
+	// VisibilityPublic ...
+	VisibilityPublic Visibility = "public"
+	// VisibilityPrivate ...
+	VisibilityPrivate Visibility = "private"
+)
+
+// domains are the domains of the services per environment.
+var domains = map[Environment]string{
+	EnvironmentProduction: "cloud.ibm.com",
+	EnvironmentStage:      "test.cloud.ibm.com",
+}
+
+// knownRegions are the regions for which endpoints can be derived.
+var knownRegions = map[string]bool{
+	"au-syd":   true,
+	"br-sao":   true,
+	"ca-tor":   true,
+	"eu-de":    true,
+	"eu-es":    true,
+	"eu-gb":    true,
+	"jp-osa":   true,
+	"jp-tok":   true,
+	"us-east":  true,
+	"us-south": true,
+}
+
+// KnownRegions returns the regions for which endpoints can be derived, sorted.
+func KnownRegions() []string {
+	regions := make([]string, 0, len(knownRegions))
+	for region := range knownRegions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// Endpoints are the URLs of the services used by the storage drivers, of one visibility.
+type Endpoints struct {
+	RIAAS         string
+	ContainersAPI string
+	IAM           string
+}
+
+// EndpointResolver derives the endpoints of a region, explicit values in cloud-conf or slclient.toml override the derived ones.
+type EndpointResolver struct {
+	// Region of the cluster, if empty the region in CloudConf is used.
+	Region string
+
+	// Environment of the cluster, if empty the environment in CloudConf is used, else EnvironmentStage if one of the
+	// endpoints in CloudConf or Config is a stage endpoint, else EnvironmentProduction.
+	// Endpoints other than IAM cannot be derived for EnvironmentCustom.
+	Environment Environment
+
+	// CloudConf if provided, its endpoints override the derived endpoints.
+	CloudConf *CloudConf
+
+	// Config (slclient.toml) if provided, its endpoints override the derived endpoints, cloud-conf takes precedence.
+	Config *Config
+}
+
+// Resolve returns the riaas, containers API and IAM endpoints of the given visibility.
+func (r EndpointResolver) Resolve(visibility Visibility) (Endpoints, error) {
+	riaas, err := r.RIAASEndpoint(visibility)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	containersAPI, err := r.ContainersAPIEndpoint(visibility)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	return Endpoints{RIAAS: riaas, ContainersAPI: containersAPI, IAM: r.IAMEndpoint(visibility)}, nil
+}
+
+// RIAASEndpoint returns the VPC riaas endpoint, for example https://us-south.iaas.cloud.ibm.com
+// or https://us-south.private.iaas.cloud.ibm.com. It is not derived for stage, it must be set in cloud-conf or slclient.toml.
+func (r EndpointResolver) RIAASEndpoint(visibility Visibility) (string, error) {
+	value, err := r.riaasEndpoint(visibility)
+	return value.Value, err
+}
+
+// ContainersAPIEndpoint returns the containers API endpoint, for example https://us-south.containers.cloud.ibm.com
+// or https://private.us-south.containers.cloud.ibm.com.
+func (r EndpointResolver) ContainersAPIEndpoint(visibility Visibility) (string, error) {
+	value, err := r.containersAPIEndpoint(visibility)
+	return value.Value, err
+}
+
+// IAMEndpoint returns the IAM endpoint, for example https://iam.cloud.ibm.com or https://private.iam.cloud.ibm.com.
+// IAM is global, an explicit token exchange URL overrides it only if it is an IAM host of the same visibility.
+func (r EndpointResolver) IAMEndpoint(visibility Visibility) string {
+	return r.iamEndpoint(visibility).Value
+}
+
+// riaasEndpoint ...
+func (r EndpointResolver) riaasEndpoint(visibility Visibility) (Value, error) {
+	var overrides []Value
+	if r.CloudConf != nil {
+		overrides = append(overrides, Value{pick(visibility, r.CloudConf.RiaasEndpoint, r.CloudConf.PrivateRIAASEndpoint), SourceCloudConf})
+	}
+	if r.Config != nil {
+		vpc := r.Config.GetVPC()
+		overrides = append(overrides, Value{pick(visibility, vpc.G2EndpointURL, vpc.G2EndpointPrivateURL), SourceSecretStore},
+			Value{pick(visibility, vpc.EndpointURL, vpc.PrivateEndpointURL), SourceSecretStore})
+	}
+	if value := firstNonEmpty(overrides...); value.Value != "" {
+		return value, nil
+	}
+
+	region, err := r.region()
+	if err != nil {
+		return Value{}, err
+	}
+	domain, err := r.domain()
+	if err != nil {
+		return Value{}, err
+	}
+	// Stage riaas hosts do not follow the production pattern (for example us-south-stage01.iaasdev.cloud.ibm.com)
+	if env := r.environment(); env == EnvironmentStage {
+		return Value{}, utils.Error{Description: fmt.Sprintf(utils.ErrDerivingEndpoints, env), Code: utils.ConfigParse}
+	}
+	if visibility == VisibilityPrivate {
+		return Value{fmt.Sprintf("https://%s.private.iaas.%s", region, domain), SourceDerived}, nil
+	}
+	return Value{fmt.Sprintf("https://%s.iaas.%s", region, domain), SourceDerived}, nil
+}
+
+// containersAPIEndpoint ...
+func (r EndpointResolver) containersAPIEndpoint(visibility Visibility) (Value, error) {
+	var overrides []Value
+	if r.CloudConf != nil {
+		overrides = append(overrides, Value{pick(visibility, r.CloudConf.ContainerAPIRoute, r.CloudConf.PrivateContainerAPIRoute), SourceCloudConf})
+	}
+	if r.Config != nil {
+		bluemix := r.Config.GetBluemix()
+		overrides = append(overrides, Value{pick(visibility, bluemix.APIEndpointURL, bluemix.PrivateAPIRoute), SourceSecretStore})
+	}
+	if value := firstNonEmpty(overrides...); value.Value != "" {
+		return value, nil
+	}
+
+	region, err := r.region()
+	if err != nil {
+		return Value{}, err
+	}
+	domain, err := r.domain()
+	if err != nil {
+		return Value{}, err
+	}
+	if visibility == VisibilityPrivate {
+		return Value{fmt.Sprintf("https://private.%s.containers.%s", region, domain), SourceDerived}, nil
+	}
+	return Value{fmt.Sprintf("https://%s.containers.%s", region, domain), SourceDerived}, nil
+}
+
+// iamEndpoint ...
+func (r EndpointResolver) iamEndpoint(visibility Visibility) Value {
+	var overrides []Value
+	if r.CloudConf != nil {
+		overrides = append(overrides, Value{r.CloudConf.TokenExchangeURL, SourceCloudConf})
+	}
+	if r.Config != nil {
+		overrides = append(overrides, Value{r.Config.GetVPC().G2TokenExchangeURL, SourceSecretStore}, Value{r.Config.GetBluemix().IamURL, SourceSecretStore})
+	}
+	for _, value := range overrides {
+		if value.Value != "" && isIAMEndpoint(value.Value) && isEndpointPrivate(value.Value) == (visibility == VisibilityPrivate) {
+			return Value{strings.TrimSuffix(value.Value, tokenExchangePath), value.Source}
+		}
+	}
+
+	// IAM of a custom environment is not known, production IAM is used as when no token exchange URL is provided.
+	domain, err := r.domain()
+	if err != nil {
+		domain = domains[EnvironmentProduction]
+	}
+	if visibility == VisibilityPrivate {
+		return Value{"https://private.iam." + domain, SourceDerived}
+	}
+	return Value{"https://iam." + domain, SourceDerived}
+}
+
+// region returns the region of the cluster, an error if it is not in knownRegions.
+func (r EndpointResolver) region() (string, error) {
+	region := r.Region
+	if region == "" && r.CloudConf != nil {
+		region = r.CloudConf.Region
+	}
+	if !knownRegions[region] {
+		return region, utils.Error{Description: fmt.Sprintf(utils.ErrUnknownRegion, region), Code: utils.ConfigParse}
+	}
+	return region, nil
+}
+
+// environment returns the environment of the cluster, see EndpointResolver.Environment.
+func (r EndpointResolver) environment() Environment {
+	env := r.Environment
+	if env == "" && r.CloudConf != nil {
+		env = r.CloudConf.Environment
+	}
+	if env != "" {
+		return env
+	}
+	for _, endpoint := range r.overrides() {
+		if info, err := ParseEndpoint(endpoint); err == nil && info.Environment == EnvironmentStage {
+			return EnvironmentStage
+		}
+	}
+	return EnvironmentProduction
+}
+
+// overrides returns the endpoints set in cloud-conf and slclient.toml which override the derived endpoints.
+func (r EndpointResolver) overrides() []string {
+	var endpoints []string
+	if cc := r.CloudConf; cc != nil {
+		endpoints = append(endpoints, cc.TokenExchangeURL, cc.RiaasEndpoint, cc.PrivateRIAASEndpoint, cc.ContainerAPIRoute, cc.PrivateContainerAPIRoute)
+	}
+	if r.Config != nil {
+		vpc, bluemix := r.Config.GetVPC(), r.Config.GetBluemix()
+		endpoints = append(endpoints, vpc.G2TokenExchangeURL, vpc.G2EndpointURL, vpc.G2EndpointPrivateURL, vpc.EndpointURL, vpc.PrivateEndpointURL,
+			bluemix.IamURL, bluemix.APIEndpointURL, bluemix.PrivateAPIRoute)
+	}
+	return endpoints
+}
+
+// domain returns the domain of the services in the environment of the cluster.
+func (r EndpointResolver) domain() (string, error) {
+	env := r.environment()
+	domain, ok := domains[env]
+	if !ok {
+		return "", utils.Error{Description: fmt.Sprintf(utils.ErrDerivingEndpoints, env), Code: utils.ConfigParse}
+	}
+	return domain, nil
+}
+
+// isIAMEndpoint checks if one of the labels of the host of url is iam, for example private.iam.cloud.ibm.com
+// or iam.bluemix.net, unlike the containers URLs also found in slclient.toml.
+func isIAMEndpoint(url string) bool {
+	info, err := ParseEndpoint(url)
+	if err != nil {
+		return false
+	}
+	for _, label := range strings.Split(info.Host, ".") {
+		if label == "iam" {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns public or private depending on the visibility.
+func pick(visibility Visibility, public, private string) string {
+	if visibility == VisibilityPrivate {
+		return private
+	}
+	return public
+}
+
+// firstNonEmpty ...
+func firstNonEmpty(values ...Value) Value {
+	for _, value := range values {
+		if value.Value != "" {
+			return value
+		}
+	}
+	return Value{}
+}
@@ -0,0 +1,283 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointResolver(t *testing.T) {
+	testcases := []struct {
+		testcasename      string
+		resolver          EndpointResolver
+		visibility        Visibility
+		expectedEndpoints Endpoints
+		expectError       bool
+	}{
+		{
+			testcasename: "Private prod endpoints",
+			resolver:     EndpointResolver{Region: "us-south"},
+			visibility:   VisibilityPrivate,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://us-south.private.iaas.cloud.ibm.com",
+				ContainersAPI: "https://private.us-south.containers.cloud.ibm.com",
+				IAM:           utils.ProdPrivateIAMURL,
+			},
+		},
+		{
+			testcasename: "Public stage endpoints",
+			resolver: EndpointResolver{
+				Region:      "eu-de",
+				Environment: EnvironmentStage,
+				Config:      &Config{VPC: &VPCProviderConfig{G2EndpointURL: "https://eu-de-stage01.iaasdev.cloud.ibm.com"}},
+			},
+			visibility: VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://eu-de-stage01.iaasdev.cloud.ibm.com",
+				ContainersAPI: "https://eu-de.containers.test.cloud.ibm.com",
+				IAM:           utils.StagePublicIAMURL,
+			},
+		},
+		{
+			testcasename: "Stage riaas endpoint is not derived",
+			resolver:     EndpointResolver{Region: "eu-de", Environment: EnvironmentStage},
+			visibility:   VisibilityPublic,
+			expectError:  true,
+		},
+		{
+			testcasename: "Region from cloud-conf",
+			resolver:     EndpointResolver{CloudConf: &CloudConf{Region: "jp-tok"}},
+			visibility:   VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://jp-tok.iaas.cloud.ibm.com",
+				ContainersAPI: "https://jp-tok.containers.cloud.ibm.com",
+				IAM:           utils.ProdPublicIAMURL,
+			},
+		},
+		{
+			testcasename: "cloud-conf overrides slclient.toml and derived endpoints",
+			resolver: EndpointResolver{
+				Region: "us-south",
+				CloudConf: &CloudConf{
+					PrivateRIAASEndpoint: "https://riaas.private.example.com",
+					TokenExchangeURL:     "https://private.iam.example.com",
+				},
+				Config: &Config{
+					VPC:     &VPCProviderConfig{G2EndpointPrivateURL: "https://riaas.g2.example.com"},
+					Bluemix: &BluemixConfig{PrivateAPIRoute: "https://private.containers.example.com"},
+				},
+			},
+			visibility: VisibilityPrivate,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://riaas.private.example.com",
+				ContainersAPI: "https://private.containers.example.com",
+				IAM:           "https://private.iam.example.com",
+			},
+		},
+		{
+			testcasename: "Token exchange URL of the other visibility is not used",
+			resolver: EndpointResolver{
+				Region: "us-east",
+				Config: &Config{VPC: &VPCProviderConfig{G2TokenExchangeURL: "https://private.iam.cloud.ibm.com/identity/token"}},
+			},
+			visibility: VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://us-east.iaas.cloud.ibm.com",
+				ContainersAPI: "https://us-east.containers.cloud.ibm.com",
+				IAM:           utils.ProdPublicIAMURL,
+			},
+		},
+		{
+			testcasename: "Environment from cloud-conf",
+			resolver: EndpointResolver{CloudConf: &CloudConf{Region: "eu-gb", Environment: EnvironmentStage,
+				PrivateRIAASEndpoint: "https://eu-gb-stage01.private.iaasdev.cloud.ibm.com"}},
+			visibility: VisibilityPrivate,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://eu-gb-stage01.private.iaasdev.cloud.ibm.com",
+				ContainersAPI: "https://private.eu-gb.containers.test.cloud.ibm.com",
+				IAM:           utils.StagePrivateIAMURL,
+			},
+		},
+		{
+			testcasename: "Custom environment with explicit endpoints",
+			resolver: EndpointResolver{
+				Environment: EnvironmentCustom,
+				CloudConf:   &CloudConf{RiaasEndpoint: "https://10.0.0.1", ContainerAPIRoute: "https://10.0.0.2"},
+			},
+			visibility: VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://10.0.0.1",
+				ContainersAPI: "https://10.0.0.2",
+				IAM:           utils.ProdPublicIAMURL,
+			},
+		},
+		{
+			testcasename: "Custom environment without explicit endpoints",
+			resolver:     EndpointResolver{Region: "us-south", Environment: EnvironmentCustom},
+			visibility:   VisibilityPublic,
+			expectError:  true,
+		},
+		{
+			testcasename: "Unknown region",
+			resolver:     EndpointResolver{Region: "mars-north"},
+			visibility:   VisibilityPrivate,
+			expectError:  true,
+		},
+		{
+			testcasename: "No region",
+			resolver:     EndpointResolver{Config: &Config{}},
+			visibility:   VisibilityPrivate,
+			expectError:  true,
+		},
+		{
+			testcasename: "Stage environment detected from a riaas override",
+			resolver: EndpointResolver{
+				CloudConf: &CloudConf{Region: "us-south", RiaasEndpoint: "https://us-south-stage01.iaasdev.cloud.ibm.com"},
+			},
+			visibility: VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://us-south-stage01.iaasdev.cloud.ibm.com",
+				ContainersAPI: "https://us-south.containers.test.cloud.ibm.com",
+				IAM:           utils.StagePublicIAMURL,
+			},
+		},
+		{
+			testcasename: "Stage environment detected from an IAM override",
+			resolver: EndpointResolver{
+				Region: "eu-de",
+				Config: &Config{
+					VPC:     &VPCProviderConfig{G2EndpointURL: "https://eu-de-stage01.iaasdev.cloud.ibm.com"},
+					Bluemix: &BluemixConfig{IamURL: "https://iam.test.cloud.ibm.com"},
+				},
+			},
+			visibility: VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://eu-de-stage01.iaasdev.cloud.ibm.com",
+				ContainersAPI: "https://eu-de.containers.test.cloud.ibm.com",
+				IAM:           "https://iam.test.cloud.ibm.com",
+			},
+		},
+		{
+			testcasename: "Production overrides",
+			resolver: EndpointResolver{
+				CloudConf: &CloudConf{Region: "us-south", RiaasEndpoint: "https://us-south.iaas.cloud.ibm.com"},
+			},
+			visibility: VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://us-south.iaas.cloud.ibm.com",
+				ContainersAPI: "https://us-south.containers.cloud.ibm.com",
+				IAM:           utils.ProdPublicIAMURL,
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			endpoints, err := testcase.resolver.Resolve(testcase.visibility)
+			if testcase.expectError {
+				assert.NotNil(t, err)
+				assert.Equal(t, utils.ConfigParse, utils.GetErrorCode(err))
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedEndpoints, endpoints)
+		})
+	}
+}
+
+func TestEndpointResolverFixtures(t *testing.T) {
+	logger, teardown := GetTestLogger(t)
+	defer teardown()
+
+	testcases := []struct {
+		testcasename      string
+		secretconfigpath  string
+		region            string
+		environment       Environment
+		visibility        Visibility
+		expectedEndpoints Endpoints
+	}{
+		{
+			testcasename:     "VPC prod, private",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/prod/slclient.toml",
+			region:           "au-syd",
+			visibility:       VisibilityPrivate,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://au-syd.private.iaas.cloud.ibm.com",
+				ContainersAPI: "https://private.au-syd.containers.cloud.ibm.com",
+				IAM:           utils.ProdPrivateIAMURL,
+			},
+		},
+		{
+			testcasename:     "VPC prod, public",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/prod/slclient.toml",
+			region:           "au-syd",
+			visibility:       VisibilityPublic,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://au-syd.iaas.cloud.ibm.com",
+				ContainersAPI: "https://au-syd.containers.cloud.ibm.com",
+				IAM:           "https://iam.bluemix.net",
+			},
+		},
+		{
+			testcasename:     "VPC stage, private",
+			secretconfigpath: "test-fixtures/valid/vpc-gen2/stage/slclient.toml",
+			region:           "us-south",
+			environment:      EnvironmentStage,
+			visibility:       VisibilityPrivate,
+			expectedEndpoints: Endpoints{
+				RIAAS:         "https://us-south-stage01.private.iaasdev.cloud.ibm.com",
+				ContainersAPI: "https://private.containers.test.cloud.ibm.com",
+				IAM:           utils.StagePrivateIAMURL,
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			pwd, err := os.Getwd()
+			if err != nil {
+				t.Errorf("Failed to get current working directory, error: %v", err)
+			}
+			byteData, err := ioutil.ReadFile(filepath.Join(pwd, "..", "..", testcase.secretconfigpath))
+			if err != nil {
+				t.Errorf("Failed to read %s, error: %v", testcase.secretconfigpath, err)
+			}
+			conf, err := ParseConfig(logger, string(byteData))
+			assert.Nil(t, err)
+
+			resolver := EndpointResolver{Region: testcase.region, Environment: testcase.environment, Config: conf}
+			endpoints, err := resolver.Resolve(testcase.visibility)
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedEndpoints, endpoints)
+			// The containers URL in iks_token_exchange_endpoint_private_url is never used as IAM.
+			assert.NotContains(t, resolver.IAMEndpoint(VisibilityPrivate), "containers")
+		})
+	}
+}
+
+func TestKnownRegions(t *testing.T) {
+	regions := KnownRegions()
+	assert.Contains(t, regions, "us-south")
+	assert.IsNonDecreasing(t, regions)
+}
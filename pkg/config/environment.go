@@ -0,0 +1,148 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+)
+
+// Environment is the IBM Cloud environment a cluster runs in.
+type Environment string
+
+const (
+	// EnvironmentProduction ...
+	EnvironmentProduction Environment = "prod"
+	// EnvironmentStage ...
+	EnvironmentStage Environment = "stage"
+	// EnvironmentCustom refers to endpoints outside the IBM Cloud domains, for example private IPs or proxies.
+	EnvironmentCustom Environment = "custom"
+)
+
+// IsValid checks if env is one of the known environments.
+func (env Environment) IsValid() bool {
+	switch env {
+	case EnvironmentProduction, EnvironmentStage, EnvironmentCustom:
+		return true
+	}
+	return false
+}
+
+// ibmCloudDomains are the domains of IBM Cloud endpoints, hosts under other domains are of EnvironmentCustom.
+var ibmCloudDomains = []string{"cloud.ibm.com", "test.ibm.com", "bluemix.net", "softlayer.com", "networklayer.com", "appdomain.cloud"}
+
+// stageLabel matches the labels of IBM Cloud hosts which mark a non production environment, for example
+// iam.test.cloud.ibm.com, iam.stage1.bluemix.net, us-south-stage01.iaasdev.cloud.ibm.com.
+var stageLabel = regexp.MustCompile(`^(test|iaasdev|stage\d*)$|-stage\d*$`)
+
+// EndpointInfo is what can be told about an endpoint from its URL.
+type EndpointInfo struct {
+	// Host of the endpoint, without port, lower case.
+	Host string
+	// Environment of the endpoint, EnvironmentCustom for hosts outside the IBM Cloud domains and IPs.
+	Environment Environment
+	// Private is true for private.* and direct.* hosts, hosts with a private label (us-south.private.iaas.cloud.ibm.com),
+	// and private, loopback or link local IPs.
+	Private bool
+	// Direct is true for direct.* hosts, the classic private endpoints.
+	Direct bool
+}
+
+// ParseEndpoint parses an endpoint, with or without scheme, port and path. Ports need not be numeric,
+// so that templates such as https://c1.us-south.containers.cloud.ibm.com:<port> in cluster-info can be parsed.
+func ParseEndpoint(endpoint string) (EndpointInfo, error) {
+	host := strings.TrimSpace(endpoint)
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+len("://"):]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if strings.HasPrefix(host, "[") {
+		// IPv6, [::1]:443
+		if i := strings.Index(host, "]"); i >= 0 {
+			host = host[1:i]
+		}
+	} else if i := strings.LastIndex(host, ":"); i >= 0 && strings.Count(host, ":") == 1 {
+		host = host[:i]
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || strings.ContainsAny(host, " <>") {
+		return EndpointInfo{}, utils.Error{Description: fmt.Sprintf(utils.ErrInvalidEndpoint, endpoint), Code: utils.ConfigParse}
+	}
+
+	info := EndpointInfo{Host: host, Environment: EnvironmentCustom}
+	if ip := net.ParseIP(host); ip != nil {
+		info.Private = ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+		return info, nil
+	}
+
+	labels := strings.Split(host, ".")
+	info.Direct = labels[0] == "direct"
+	for _, label := range labels {
+		if label == "private" {
+			info.Private = true
+		}
+	}
+	info.Private = info.Private || info.Direct
+
+	for _, domain := range ibmCloudDomains {
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		info.Environment = EnvironmentProduction
+		for _, label := range labels {
+			if stageLabel.MatchString(label) {
+				info.Environment = EnvironmentStage
+				break
+			}
+		}
+		break
+	}
+	return info, nil
+}
+
+// isEndpointPrivate determines if the provided url is private or public endpoint
+func isEndpointPrivate(url string) bool {
+	info, err := ParseEndpoint(url)
+	return err == nil && info.Private
+}
+
+// isProduction determines if the env in which a pod is deployed is stage or production,
+// endpoints of custom environments and endpoints which cannot be parsed are treated as production.
+func isProduction(url string) bool {
+	info, err := ParseEndpoint(url)
+	return err != nil || info.Environment != EnvironmentStage
+}
+
+// environmentOf returns override if set, else the production or stage environment of url, see isProduction.
+func environmentOf(url string, override Environment) Environment {
+	if override != "" {
+		return override
+	}
+	if isProduction(url) {
+		return EnvironmentProduction
+	}
+	return EnvironmentStage
+}
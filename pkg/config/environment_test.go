@@ -0,0 +1,157 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	testcases := []struct {
+		testcasename string
+		endpoint     string
+		expectedInfo EndpointInfo
+		expectError  bool
+	}{
+		{
+			testcasename: "Production public IAM",
+			endpoint:     "https://iam.cloud.ibm.com",
+			expectedInfo: EndpointInfo{Host: "iam.cloud.ibm.com", Environment: EnvironmentProduction},
+		},
+		{
+			testcasename: "Production private IAM with token path",
+			endpoint:     "https://private.iam.cloud.ibm.com/identity/token",
+			expectedInfo: EndpointInfo{Host: "private.iam.cloud.ibm.com", Environment: EnvironmentProduction, Private: true},
+		},
+		{
+			testcasename: "Stage private IAM",
+			endpoint:     "https://private.iam.test.cloud.ibm.com",
+			expectedInfo: EndpointInfo{Host: "private.iam.test.cloud.ibm.com", Environment: EnvironmentStage, Private: true},
+		},
+		{
+			testcasename: "Legacy stage IAM",
+			endpoint:     "https://iam.stage1.bluemix.net",
+			expectedInfo: EndpointInfo{Host: "iam.stage1.bluemix.net", Environment: EnvironmentStage},
+		},
+		{
+			testcasename: "Legacy production IAM",
+			endpoint:     "https://iam.bluemix.net",
+			expectedInfo: EndpointInfo{Host: "iam.bluemix.net", Environment: EnvironmentProduction},
+		},
+		{
+			testcasename: "Private regional riaas",
+			endpoint:     "https://us-south.private.iaas.cloud.ibm.com:443",
+			expectedInfo: EndpointInfo{Host: "us-south.private.iaas.cloud.ibm.com", Environment: EnvironmentProduction, Private: true},
+		},
+		{
+			testcasename: "Stage riaas",
+			endpoint:     "https://us-south-stage01.iaasdev.cloud.ibm.com",
+			expectedInfo: EndpointInfo{Host: "us-south-stage01.iaasdev.cloud.ibm.com", Environment: EnvironmentStage},
+		},
+		{
+			testcasename: "Dev containers master URL with port template, dev is not a stage label",
+			endpoint:     "https://c101.containers.dev.cloud.ibm.com:<port>",
+			expectedInfo: EndpointInfo{Host: "c101.containers.dev.cloud.ibm.com", Environment: EnvironmentProduction},
+		},
+		{
+			testcasename: "Satellite master URL",
+			endpoint:     "https://sat-link.us-east.satellite.appdomain.cloud:30395",
+			expectedInfo: EndpointInfo{Host: "sat-link.us-east.satellite.appdomain.cloud", Environment: EnvironmentProduction},
+		},
+		{
+			testcasename: "Direct endpoint",
+			endpoint:     "https://direct.iam.cloud.ibm.com",
+			expectedInfo: EndpointInfo{Host: "direct.iam.cloud.ibm.com", Environment: EnvironmentProduction, Private: true, Direct: true},
+		},
+		{
+			testcasename: "Classic softlayer API",
+			endpoint:     "https://api.service.softlayer.com/rest/v3",
+			expectedInfo: EndpointInfo{Host: "api.service.softlayer.com", Environment: EnvironmentProduction},
+		},
+		{
+			testcasename: "Host without scheme",
+			endpoint:     "Private.IAM.cloud.ibm.com",
+			expectedInfo: EndpointInfo{Host: "private.iam.cloud.ibm.com", Environment: EnvironmentProduction, Private: true},
+		},
+		{
+			testcasename: "Custom host containing test and private",
+			endpoint:     "https://teststorage.example/privateapi",
+			expectedInfo: EndpointInfo{Host: "teststorage.example", Environment: EnvironmentCustom},
+		},
+		{
+			testcasename: "Custom host with a test label",
+			endpoint:     "https://iam.test.example.com",
+			expectedInfo: EndpointInfo{Host: "iam.test.example.com", Environment: EnvironmentCustom},
+		},
+		{
+			testcasename: "Private IP",
+			endpoint:     "https://10.0.0.1:8443/identity/token",
+			expectedInfo: EndpointInfo{Host: "10.0.0.1", Environment: EnvironmentCustom, Private: true},
+		},
+		{
+			testcasename: "Public IP",
+			endpoint:     "http://169.60.1.1",
+			expectedInfo: EndpointInfo{Host: "169.60.1.1", Environment: EnvironmentCustom},
+		},
+		{
+			testcasename: "IPv6 loopback",
+			endpoint:     "https://[::1]:443",
+			expectedInfo: EndpointInfo{Host: "::1", Environment: EnvironmentCustom, Private: true},
+		},
+		{
+			testcasename: "Empty endpoint",
+			endpoint:     "",
+			expectError:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			info, err := ParseEndpoint(testcase.endpoint)
+			if testcase.expectError {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expectedInfo, info)
+		})
+	}
+}
+
+func TestIsProduction(t *testing.T) {
+	testcases := []struct {
+		testcasename string
+		url          string
+		isProduction bool
+		isPrivate    bool
+	}{
+		{testcasename: "Production", url: "https://private.iam.cloud.ibm.com", isProduction: true, isPrivate: true},
+		{testcasename: "Stage", url: "https://iam.test.cloud.ibm.com", isProduction: false},
+		{testcasename: "Custom host containing test", url: "https://teststorage.example", isProduction: true},
+		{testcasename: "Custom host containing stage and private", url: "https://backstage.example/private", isProduction: true},
+		{testcasename: "Empty", url: "", isProduction: true},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			assert.Equal(t, testcase.isProduction, isProduction(testcase.url))
+			assert.Equal(t, testcase.isPrivate, isEndpointPrivate(testcase.url))
+		})
+	}
+}
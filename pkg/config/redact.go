@@ -0,0 +1,137 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap/zapcore"
+)
+
+// MarshalLogObject logs the sections of the config which are set, masking the secret-bearing fields.
+func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	sections := []struct {
+		name    string
+		section zapcore.ObjectMarshaler
+		isNil   bool
+	}{
+		{"server", c.Server, c.Server == nil},
+		{"bluemix", c.Bluemix, c.Bluemix == nil},
+		{"softlayer", c.Softlayer, c.Softlayer == nil},
+		{"vpc", c.VPC, c.VPC == nil},
+		{"iks", c.IKS, c.IKS == nil},
+		{"api", c.API, c.API == nil},
+	}
+	for _, s := range sections {
+		if s.isNil {
+			continue
+		}
+		if err := enc.AddObject(s.name, s.section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalLogObject ...
+func (sc *ServerConfig) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(enc, sc)
+}
+
+// MarshalLogObject ...
+func (bc *BluemixConfig) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(enc, bc)
+}
+
+// MarshalLogObject ...
+func (sc *SoftlayerConfig) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(enc, sc)
+}
+
+// MarshalLogObject ...
+func (vc *VPCProviderConfig) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(enc, vc)
+}
+
+// MarshalLogObject ...
+func (ic *IKSConfig) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(enc, ic)
+}
+
+// MarshalLogObject ...
+func (ac *APIConfig) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalRedacted(enc, ac)
+}
+
+// MarshalLogObject ...
+func (cc ClusterConfig) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("cluster_id", cc.ClusterID)
+	enc.AddString("cluster_name", cc.ClusterName)
+	enc.AddString("master_url", cc.MasterURL)
+	enc.AddString("master_public_url", cc.MasterPublicURL)
+	enc.AddString("cluster_provider", cc.ClusterProvider)
+	enc.AddString("cluster_type", cc.ClusterType)
+	enc.AddString("account_id", cc.AccountID)
+	enc.AddString("region", cc.Region)
+	enc.AddString("datacenter", cc.Datacenter)
+	enc.AddString("crn", cc.CRN)
+	// Fields not defined in ClusterConfig are logged too, masked if their name suggests a secret.
+	for _, name := range cc.ExtraFieldNames() {
+		value := string(cc.extra[name])
+		if utils.IsSensitiveKey(name) {
+			value = utils.Redact(value)
+		}
+		enc.AddString(name, value)
+	}
+	return nil
+}
+
+// marshalRedacted logs the fields of the config section pointed by section, under their toml names.
+// Fields tagged json:"-" carry secrets (api keys, client secrets, tokens), their value is masked.
+func marshalRedacted(enc zapcore.ObjectEncoder, section interface{}) error {
+	value := reflect.ValueOf(section)
+	if value.IsNil() {
+		return nil
+	}
+	value = value.Elem()
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldValue := value.Field(i)
+		if field.Tag.Get("json") == "-" {
+			enc.AddString(name, utils.Redact(fieldValue.String()))
+			continue
+		}
+
+		switch fieldValue.Kind() {
+		case reflect.String:
+			enc.AddString(name, fieldValue.String())
+		case reflect.Bool:
+			enc.AddBool(name, fieldValue.Bool())
+		case reflect.Int:
+			enc.AddInt64(name, fieldValue.Int())
+		}
+	}
+	return nil
+}
@@ -17,6 +17,11 @@
 package config
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
 	"github.com/IBM/secret-utils-lib/pkg/utils"
 
 	"github.com/BurntSushi/toml"
@@ -32,6 +37,12 @@ type Config struct {
 	VPC       *VPCProviderConfig
 	IKS       *IKSConfig
 	API       *APIConfig
+
+	// sections are the sections defined in slclient.toml, set by ParseConfig. The sections missing from slclient.toml
+	// are allocated when reading the environment variables, so the pointers alone do not tell if a section was provided.
+	sections map[string]bool
+	// undecodedKeys are the keys of slclient.toml which no field of Config covers, set by ParseConfig.
+	undecodedKeys []string
 }
 
 // ServerConfig configuration options for the provider server itself
@@ -128,20 +139,88 @@ type APIConfig struct {
 	PassthroughSecret string `toml:"PassthroughSecret" json:"-"`
 }
 
+// unknownKeysWarned holds the sha256 of the configs whose unknown keys were already logged as a warning, the config is
+// parsed each time the secret is read and the warning is only useful once per content of the secret.
+var unknownKeysWarned sync.Map
+
+// ParseOptions ...
+type ParseOptions struct {
+	// Strict fails parsing if slclient.toml has keys which no field of Config covers (for example a misspelled key),
+	// instead of logging a warning.
+	Strict bool
+}
+
 // ParseConfig loads the config from file
 func ParseConfig(logger *zap.Logger, data string) (*Config, error) {
+	return ParseConfigWithOptions(logger, data, ParseOptions{})
+}
+
+// ParseConfigWithOptions is ParseConfig, the keys of slclient.toml which no field of Config covers are reported as
+// configured by opts, see UndecodedKeys.
+func ParseConfigWithOptions(logger *zap.Logger, data string, opts ParseOptions) (*Config, error) {
 	configData := new(Config)
-	_, err := toml.Decode(data, configData)
+	md, err := toml.Decode(data, configData)
 	if err != nil {
+		err = redactParseError(err)
 		logger.Error("Failed to parse config", zap.Error(err))
-		return nil, utils.Error{Description: utils.ErrParsingConfig, BackendError: err.Error()}
+		return nil, utils.Error{Description: utils.ErrParsingConfig, BackendError: err.Error(), Code: utils.ConfigParse, Err: err}
+	}
+
+	configData.sections = make(map[string]bool)
+	for _, key := range md.Keys() {
+		if len(key) == 1 {
+			configData.sections[key[0]] = true
+		}
+	}
+
+	// Only the names of the keys are kept, their values can be credentials
+	for _, key := range md.Undecoded() {
+		configData.undecodedKeys = append(configData.undecodedKeys, key.String())
+	}
+	if len(configData.undecodedKeys) != 0 {
+		if opts.Strict {
+			var errs fieldErrors
+			for _, key := range configData.undecodedKeys {
+				errs.add(key, "", "unknown key")
+			}
+			err = errs.err()
+			logger.Error("Unknown keys in config", zap.Strings("keys", configData.undecodedKeys))
+			return nil, utils.Error{Description: utils.ErrUnknownConfigKeys, BackendError: err.Error(), Code: utils.ConfigParse, Err: err}
+		}
+		if _, warned := unknownKeysWarned.LoadOrStore(sha256.Sum256([]byte(data)), true); warned {
+			logger.Debug("Ignoring unknown keys in config", zap.Strings("keys", configData.undecodedKeys))
+		} else {
+			logger.Warn("Ignoring unknown keys in config", zap.Strings("keys", configData.undecodedKeys))
+		}
 	}
 
 	err = envconfig.Process("", configData)
 	if err != nil {
 		logger.Error("Failed to gather environment config variable", zap.Error(err))
-		return nil, utils.Error{Description: utils.ErrFetchingENV, BackendError: err.Error()}
+		return nil, utils.Error{Description: utils.ErrFetchingENV, BackendError: err.Error(), Code: utils.ConfigParse, Err: err}
 	}
 
 	return configData, nil
 }
+
+// UndecodedKeys returns the keys of slclient.toml which no field of Config covers, for example Bluemix.pay_tier.
+// Their values are ignored.
+func (c *Config) UndecodedKeys() []string {
+	if c == nil {
+		return nil
+	}
+	return c.undecodedKeys
+}
+
+// redactParseError drops the message of toml syntax errors, which can quote the value being parsed (for example
+// a part of an unquoted api key), keeping the line and key at which parsing failed.
+func redactParseError(err error) error {
+	var parseErr toml.ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+	if parseErr.LastKey == "" {
+		return fmt.Errorf("toml: line %d: invalid syntax", parseErr.Position.Line)
+	}
+	return fmt.Errorf("toml: line %d (last key %q): invalid syntax", parseErr.Position.Line, parseErr.LastKey)
+}
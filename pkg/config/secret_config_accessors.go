@@ -0,0 +1,139 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+)
+
+// GetBluemix returns the Bluemix section, an empty section if it is missing from slclient.toml.
+func (c *Config) GetBluemix() BluemixConfig {
+	if c == nil || c.Bluemix == nil {
+		return BluemixConfig{}
+	}
+	return *c.Bluemix
+}
+
+// GetSoftlayer returns the Softlayer section, an empty section if it is missing from slclient.toml.
+func (c *Config) GetSoftlayer() SoftlayerConfig {
+	if c == nil || c.Softlayer == nil {
+		return SoftlayerConfig{}
+	}
+	return *c.Softlayer
+}
+
+// GetVPC returns the VPC section, an empty section if it is missing from slclient.toml.
+func (c *Config) GetVPC() VPCProviderConfig {
+	if c == nil || c.VPC == nil {
+		return VPCProviderConfig{}
+	}
+	return *c.VPC
+}
+
+// GetIKS returns the IKS section, an empty section if it is missing from slclient.toml.
+func (c *Config) GetIKS() IKSConfig {
+	if c == nil || c.IKS == nil {
+		return IKSConfig{}
+	}
+	return *c.IKS
+}
+
+// GetAPI returns the API section, an empty section if it is missing from slclient.toml.
+func (c *Config) GetAPI() APIConfig {
+	if c == nil || c.API == nil {
+		return APIConfig{}
+	}
+	return *c.API
+}
+
+// APIKey returns the api key of the provider (vpc, bluemix, softlayer), empty if the provider is unknown
+// or its section is missing.
+func (c *Config) APIKey(providerType string) string {
+	switch providerType {
+	case utils.VPC:
+		return c.GetVPC().G2APIKey
+	case utils.Bluemix:
+		return c.GetBluemix().IamAPIKey
+	case utils.Softlayer:
+		return c.GetSoftlayer().SoftlayerAPIKey
+	}
+	return ""
+}
+
+// TokenExchangeURL returns the token exchange URL of the provider (vpc, bluemix, softlayer), empty if the provider
+// is unknown or its section is missing.
+func (c *Config) TokenExchangeURL(providerType string) string {
+	switch providerType {
+	case utils.VPC:
+		return c.GetVPC().G2TokenExchangeURL
+	case utils.Bluemix:
+		return c.GetBluemix().IamURL
+	case utils.Softlayer:
+		return c.GetSoftlayer().SoftlayerTokenExchangeURL
+	}
+	return ""
+}
+
+// Encryption returns whether the api key of the provider is encrypted, softlayer api keys never are.
+func (c *Config) Encryption(providerType string) bool {
+	switch providerType {
+	case utils.VPC:
+		return c.GetVPC().Encryption
+	case utils.Bluemix:
+		return c.GetBluemix().Encryption
+	}
+	return false
+}
+
+// Validate checks that slclient.toml can be used to authenticate as the provider (vpc, bluemix, softlayer),
+// that is the section of the provider is present and has an api key.
+// The error returned is a ValidationError listing every problem found.
+func (c *Config) Validate(providerType string) error {
+	var errs fieldErrors
+	if c == nil {
+		c = &Config{}
+	}
+	var section, apiKeyField string
+	var present bool
+	switch providerType {
+	case utils.VPC:
+		section, apiKeyField, present = "VPC", "g2_api_key", c.hasSection("VPC", c.VPC != nil)
+	case utils.Bluemix:
+		section, apiKeyField, present = "Bluemix", "iam_api_key", c.hasSection("Bluemix", c.Bluemix != nil)
+	case utils.Softlayer:
+		section, apiKeyField, present = "Softlayer", "softlayer_api_key", c.hasSection("Softlayer", c.Softlayer != nil)
+	default:
+		errs.add("provider", providerType, "must be one of vpc, bluemix, softlayer")
+		return errs.err()
+	}
+
+	if !present {
+		errs.add("["+section+"]", "", "section is missing")
+		return errs.err()
+	}
+	errs.required(section+"."+apiKeyField, c.APIKey(providerType))
+	return errs.err()
+}
+
+// hasSection checks if the section was provided in slclient.toml, allocated tells if its pointer is set.
+func (c *Config) hasSection(name string, allocated bool) bool {
+	if c.sections == nil {
+		// Not parsed by ParseConfig
+		return allocated
+	}
+	return allocated && c.sections[name]
+}
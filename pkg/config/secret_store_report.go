@@ -0,0 +1,119 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// providerSections are the providers which can be validated by ValidateSecretStore, with the name of their section.
+var providerSections = []struct {
+	provider string
+	section  string
+}{
+	{utils.VPC, "VPC"},
+	{utils.Bluemix, "Bluemix"},
+	{utils.Softlayer, "Softlayer"},
+}
+
+// ProviderCheck is the result of validating slclient.toml for a provider.
+type ProviderCheck struct {
+	Provider string
+	// Err is the error returned by Config.Validate, nil if slclient.toml can be used for the provider.
+	Err error
+}
+
+// SecretStoreReport is the result of ValidateSecretStore. It never holds the values of slclient.toml.
+type SecretStoreReport struct {
+	// ParseErr is set if slclient.toml cannot be parsed, nothing else is checked then.
+	ParseErr error
+	// UnknownKeys are the keys which no field of Config covers, they are ignored unless parsing is strict.
+	UnknownKeys []string
+	// Providers are the results of validating slclient.toml for each provider.
+	Providers []ProviderCheck
+}
+
+// ValidateSecretStore checks slclient.toml (the data of the storage-secret-store secret) before it is applied.
+// It is parsed, its unknown keys are listed and it is validated for providers (vpc, bluemix, softlayer).
+// If no provider is given, it is validated for every provider whose section is present.
+func ValidateSecretStore(logger *zap.Logger, data string, providers ...string) SecretStoreReport {
+	var report SecretStoreReport
+	conf, err := ParseConfig(logger, data)
+	if err != nil {
+		report.ParseErr = err
+		return report
+	}
+	report.UnknownKeys = conf.UndecodedKeys()
+
+	if len(providers) == 0 {
+		for _, ps := range providerSections {
+			if conf.sections[ps.section] {
+				providers = append(providers, ps.provider)
+			}
+		}
+	}
+	if len(providers) == 0 {
+		var errs fieldErrors
+		errs.add("provider", "", "none of the [VPC], [Bluemix] or [Softlayer] sections is present")
+		report.Providers = append(report.Providers, ProviderCheck{Err: errs.err()})
+		return report
+	}
+	for _, provider := range providers {
+		report.Providers = append(report.Providers, ProviderCheck{Provider: provider, Err: conf.Validate(provider)})
+	}
+	return report
+}
+
+// OK reports whether slclient.toml can be applied, if strict unknown keys are not allowed either.
+func (r SecretStoreReport) OK(strict bool) bool {
+	if r.ParseErr != nil || (strict && len(r.UnknownKeys) != 0) {
+		return false
+	}
+	for _, check := range r.Providers {
+		if check.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the report for operators, one finding per line.
+func (r SecretStoreReport) String() string {
+	if r.ParseErr != nil {
+		return fmt.Sprintf("ERROR parse: %v", r.ParseErr)
+	}
+	var lines []string
+	for _, key := range r.UnknownKeys {
+		lines = append(lines, fmt.Sprintf("WARN  %s: unknown key, ignored", key))
+	}
+	for _, check := range r.Providers {
+		switch {
+		case check.Err != nil && check.Provider == "":
+			lines = append(lines, fmt.Sprintf("ERROR %v", check.Err))
+			continue
+		case check.Err != nil:
+			lines = append(lines, fmt.Sprintf("ERROR %s: %v", check.Provider, check.Err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("OK    %s", check.Provider))
+	}
+	return strings.Join(lines, "\n")
+}
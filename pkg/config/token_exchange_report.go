@@ -0,0 +1,108 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rules by which ResolveTokenExchangeURL chooses the token exchange URL. The environment is the one in cloud-conf if set,
+// else the one of the token exchange URL of the provider in slclient.toml for VPC clusters, and of the master URL in cluster-info otherwise.
+const (
+	// RuleCloudConf ...
+	RuleCloudConf = "token_exchange_url provided in cloud-conf"
+	// RuleClusterInfoUnavailable ...
+	RuleClusterInfoUnavailable = "cluster-info unavailable, defaulting to production private IAM"
+	// RuleVPCPrivateURLProvided ...
+	RuleVPCPrivateURLProvided = "VPC cluster, private token exchange URL of the provider provided in slclient.toml"
+	// RuleVPCProdPrivateIAM ...
+	RuleVPCProdPrivateIAM = "VPC cluster, no private token exchange URL of the provider in slclient.toml, production environment, using production private IAM"
+	// RuleVPCStagePrivateIAM ...
+	RuleVPCStagePrivateIAM = "VPC cluster, no private token exchange URL of the provider in slclient.toml, stage environment, using stage private IAM"
+	// RuleProviderURLProvided ...
+	RuleProviderURLProvided = "non-VPC cluster, token exchange URL of the provider provided in slclient.toml"
+	// RuleSatelliteProdPublicIAM ...
+	RuleSatelliteProdPublicIAM = "satellite cluster (from cluster-info), production environment, using production public IAM"
+	// RuleSatelliteStagePublicIAM ...
+	RuleSatelliteStagePublicIAM = "satellite cluster (from cluster-info), stage environment, using stage public IAM"
+	// RuleProdPrivateIAM ...
+	RuleProdPrivateIAM = "non-satellite cluster (from cluster-info), production environment, using production private IAM"
+	// RuleStagePrivateIAM ...
+	RuleStagePrivateIAM = "non-satellite cluster (from cluster-info), stage environment, using stage private IAM"
+)
+
+// ResolutionStep is a source consulted while resolving the token exchange URL.
+type ResolutionStep struct {
+	// Source consulted.
+	Source Source
+	// Detail identifies the data read, for example the namespace, secret and key of slclient.toml.
+	Detail string
+	// Value read from the source, the token exchange URL, or the cluster type and provider for cluster-info.
+	Value string
+	// Err is the error reading or parsing the source, nil if it was read.
+	Err error
+}
+
+// String ...
+func (step ResolutionStep) String() string {
+	if step.Err != nil {
+		return fmt.Sprintf("%s (%s): error: %v", step.Source, step.Detail, step.Err)
+	}
+	return fmt.Sprintf("%s (%s): %q", step.Source, step.Detail, step.Value)
+}
+
+// TokenExchangeURLReport explains how ResolveTokenExchangeURL chose the token exchange URL.
+type TokenExchangeURLReport struct {
+	// Steps are the sources consulted, in order.
+	Steps []ResolutionStep
+	// URL is the token exchange URL chosen.
+	URL string
+	// IsURLProvided is false if the URL was defaulted to private IAM, in which case the authenticator may fall back to public IAM.
+	IsURLProvided bool
+	// Rule which chose the URL and IsURLProvided, one of the Rule constants.
+	Rule string
+}
+
+// String formats the report, one line per step followed by the result.
+func (report TokenExchangeURLReport) String() string {
+	var sb strings.Builder
+	for i, step := range report.Steps {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, step)
+	}
+	fmt.Fprintf(&sb, "result: %s (isURLprovided: %t), rule: %s", report.URL, report.IsURLProvided, report.Rule)
+	return sb.String()
+}
+
+// addStep ...
+func (report *TokenExchangeURLReport) addStep(source Source, detail, value string, err error) {
+	if err != nil {
+		value = ""
+	}
+	report.Steps = append(report.Steps, ResolutionStep{Source: source, Detail: detail, Value: value, Err: err})
+}
+
+// resolved sets the result of the report and returns it.
+func (report TokenExchangeURLReport) resolved(url string, isURLprovided bool, rule string) TokenExchangeURLReport {
+	report.URL, report.IsURLProvided, report.Rule = url, isURLprovided, rule
+	return report
+}
+
+// clusterInfoSummary is the value of cluster-info recorded in the report.
+func clusterInfoSummary(cc ClusterConfig) string {
+	return fmt.Sprintf("kind=%s cluster_type=%s cluster_provider=%s master_url=%s", cc.Kind(), cc.ClusterType, cc.ClusterProvider, cc.MasterURL)
+}
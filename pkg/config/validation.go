@@ -0,0 +1,94 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FieldError is a field of a config which failed validation.
+type FieldError struct {
+	// Field is the name of the field in the config (json or toml name).
+	Field string
+	// Value is the value of the field, empty for missing fields. It is never set for secret-bearing fields.
+	Value string
+	// Reason the value is invalid.
+	Reason string
+}
+
+// Error ...
+func (fe FieldError) Error() string {
+	if fe.Value == "" {
+		return fmt.Sprintf("%s: %s", fe.Field, fe.Reason)
+	}
+	return fmt.Sprintf("%s: %s (value: %s)", fe.Field, fe.Reason, fe.Value)
+}
+
+// ValidationError lists every field of a config which failed validation.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error ...
+func (ve ValidationError) Error() string {
+	errs := make([]string, 0, len(ve.Errors))
+	for _, fe := range ve.Errors {
+		errs = append(errs, fe.Error())
+	}
+	return strings.Join(errs, "; ")
+}
+
+// fieldErrors accumulates the field errors found while validating a config.
+type fieldErrors []FieldError
+
+// add ...
+func (fes *fieldErrors) add(field, value, reason string) {
+	*fes = append(*fes, FieldError{Field: field, Value: value, Reason: reason})
+}
+
+// required adds an error if value is empty.
+func (fes *fieldErrors) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		fes.add(field, "", "is required")
+		return false
+	}
+	return true
+}
+
+// httpsURL adds an error if value is not an absolute https URL, and returns the parsed URL if it is.
+func (fes *fieldErrors) httpsURL(field, value string) *url.URL {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		fes.add(field, value, "is not an absolute URL")
+		return nil
+	}
+	if u.Scheme != "https" {
+		fes.add(field, value, "must use the https scheme")
+		return nil
+	}
+	return u
+}
+
+// err returns a ValidationError if any field failed validation.
+func (fes fieldErrors) err() error {
+	if len(fes) == 0 {
+		return nil
+	}
+	return ValidationError{Errors: fes}
+}
@@ -10,10 +10,22 @@ import (
 
 // GetConfigMapData ...
 func GetConfigMapData(kc KubernetesClient, configMapName, dataName string) (string, error) {
+	return GetConfigMapDataWithContext(context.Background(), kc, configMapName, dataName)
+}
+
+// GetConfigMapDataWithContext is GetConfigMapData, recording a GetConfigMapData span in ctx.
+func GetConfigMapDataWithContext(ctx context.Context, kc KubernetesClient, configMapName, dataName string) (data string, err error) {
+	ctx, span := utils.StartSpan(ctx, "GetConfigMapData", utils.AttrNamespace.String(kc.Namespace),
+		utils.AttrConfigMapName.String(configMapName), utils.AttrKey.String(dataName))
+	defer func() { utils.EndSpan(span, err) }()
+
+	if kc.Clientset == nil {
+		return "", utils.Error{Description: utils.ErrK8sClientUndefined}
+	}
 
-	cm, err := kc.Clientset.CoreV1().ConfigMaps(kc.Namespace).Get(context.TODO(), configMapName, metav1.GetOptions{})
+	cm, err := kc.Clientset.CoreV1().ConfigMaps(kc.Namespace).Get(ctx, configMapName, metav1.GetOptions{})
 	if err != nil {
-		return "", err
+		return "", utils.Error{Description: fmt.Sprintf(utils.ErrFetchingConfigMap, configMapName), BackendError: err.Error(), Err: err}
 	}
 
 	data, ok := cm.Data[dataName]
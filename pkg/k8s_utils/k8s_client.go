@@ -23,6 +23,7 @@ import (
 	"github.com/IBM/secret-utils-lib/pkg/utils"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
@@ -43,13 +44,13 @@ func Getk8sClientSet() (KubernetesClient, error) {
 	// Fetching cluster config used to create k8s client
 	k8sConfig, err := rest.InClusterConfig()
 	if err != nil {
-		return kc, utils.Error{Description: utils.ErrFetchingK8sClusterConfig, BackendError: err.Error()}
+		return kc, utils.Error{Description: utils.ErrFetchingK8sClusterConfig, BackendError: err.Error(), Err: err}
 	}
 
 	// Creating k8s client used to read secret
 	clientset, err := kubernetes.NewForConfig(k8sConfig)
 	if err != nil {
-		return kc, utils.Error{Description: utils.ErrFetchingK8sClusterConfig, BackendError: err.Error()}
+		return kc, utils.Error{Description: utils.ErrFetchingK8sClusterConfig, BackendError: err.Error(), Err: err}
 	}
 
 	namespace, err := getNameSpace()
@@ -62,12 +63,48 @@ func Getk8sClientSet() (KubernetesClient, error) {
 	return kc, nil
 }
 
+// Getk8sClientSetFromKubeconfig creates the k8s client using a kubeconfig, so that the library can be used outside the cluster.
+// The kubeconfig is loaded from kubeconfigPath if provided, else from the KUBECONFIG env variable or the default location (~/.kube/config).
+// kubeContext, if provided, is used instead of the current context.
+// namespace, if provided, is where secrets and config maps are read from, else the namespace of the selected context is used.
+func Getk8sClientSetFromKubeconfig(kubeconfigPath, kubeContext, namespace string) (KubernetesClient, error) {
+
+	var kc KubernetesClient
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfigPath
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	overrides.Context.Namespace = namespace
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	k8sConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return kc, utils.Error{Description: utils.ErrFetchingK8sClusterConfig, BackendError: err.Error(), Err: err}
+	}
+
+	// Creating k8s client used to read secret
+	clientset, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return kc, utils.Error{Description: utils.ErrFetchingK8sClusterConfig, BackendError: err.Error(), Err: err}
+	}
+
+	// Namespace returns the namespace override if provided, else the one from the context ("default" if unset)
+	kcNamespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return kc, utils.Error{Description: utils.ErrFetchingNamespace, BackendError: err.Error(), Err: err}
+	}
+
+	kc.Clientset = clientset
+	kc.Namespace = kcNamespace
+	return kc, nil
+}
+
 // getNameSpace ...
 func getNameSpace() (string, error) {
 	// Reading the namespace in which the pod is deployed
 	byteData, err := ioutil.ReadFile(nameSpacePath)
 	if err != nil {
-		return "", utils.Error{Description: utils.ErrFetchingNamespace, BackendError: err.Error()}
+		return "", utils.Error{Description: utils.ErrFetchingNamespace, BackendError: err.Error(), Err: err}
 	}
 
 	namespace := string(byteData)
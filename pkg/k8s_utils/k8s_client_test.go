@@ -0,0 +1,102 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s_utils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: current
+  context:
+    cluster: cluster
+    namespace: current-ns
+- name: other
+  context:
+    cluster: cluster
+current-context: current
+`
+
+func TestGetk8sClientSetFromKubeconfig(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := ioutil.WriteFile(kubeconfigPath, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig, error: %v", err)
+	}
+
+	testcases := []struct {
+		testcasename      string
+		kubeconfigPath    string
+		kubeconfigEnv     string
+		kubeContext       string
+		namespace         string
+		expectedNamespace string
+		expectError       bool
+	}{
+		{
+			testcasename:      "Explicit path, current context",
+			kubeconfigPath:    kubeconfigPath,
+			expectedNamespace: "current-ns",
+		},
+		{
+			testcasename:      "KUBECONFIG env, context without namespace",
+			kubeconfigEnv:     kubeconfigPath,
+			kubeContext:       "other",
+			expectedNamespace: "default",
+		},
+		{
+			testcasename:      "Explicit namespace",
+			kubeconfigPath:    kubeconfigPath,
+			namespace:         "kube-system",
+			expectedNamespace: "kube-system",
+		},
+		{
+			testcasename:   "Unknown context",
+			kubeconfigPath: kubeconfigPath,
+			kubeContext:    "unknown",
+			expectError:    true,
+		},
+		{
+			testcasename:   "Kubeconfig does not exist",
+			kubeconfigPath: filepath.Join(t.TempDir(), "does-not-exist"),
+			expectError:    true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			t.Setenv("KUBECONFIG", testcase.kubeconfigEnv)
+			kc, err := Getk8sClientSetFromKubeconfig(testcase.kubeconfigPath, testcase.kubeContext, testcase.namespace)
+			if testcase.expectError {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.NotNil(t, kc.Clientset)
+			assert.Equal(t, testcase.expectedNamespace, kc.Namespace)
+		})
+	}
+}
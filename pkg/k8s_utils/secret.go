@@ -19,29 +19,88 @@ package k8s_utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
 
 	"github.com/IBM/secret-utils-lib/pkg/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// SecretSource identifies a key in a k8s secret.
+type SecretSource struct {
+	// Namespace of the secret, if empty, the namespace of the k8s client is used.
+	Namespace  string
+	SecretName string
+	Key        string
+}
+
 // GetSecretData ...
 func GetSecretData(kc KubernetesClient, secretName, secretKey string) (string, error) {
+	return GetSecretDataFromSource(kc, SecretSource{SecretName: secretName, Key: secretKey})
+}
+
+// GetSecretDataFromSource reads the data stored under the key of the secret identified by source.
+func GetSecretDataFromSource(kc KubernetesClient, source SecretSource) (string, error) {
+	return GetSecretDataFromSourceWithContext(context.Background(), kc, source)
+}
+
+// GetSecretDataFromSourceWithContext is GetSecretDataFromSource, recording a GetSecretData span in ctx.
+func GetSecretDataFromSourceWithContext(ctx context.Context, kc KubernetesClient, source SecretSource) (data string, err error) {
+	namespace := source.Namespace
+	if namespace == "" {
+		namespace = kc.Namespace
+	}
+
+	ctx, span := utils.StartSpan(ctx, "GetSecretData", utils.AttrNamespace.String(namespace),
+		utils.AttrSecretName.String(source.SecretName), utils.AttrKey.String(source.Key))
+	defer func() { utils.EndSpan(span, err) }()
 
-	secret, err := kc.Clientset.CoreV1().Secrets(kc.Namespace).Get(context.TODO(), secretName, v1.GetOptions{})
+	if kc.Clientset == nil {
+		return "", utils.Error{Description: utils.ErrK8sClientUndefined}
+	}
+
+	secretName, secretKey := source.SecretName, source.Key
+	secret, err := kc.Clientset.CoreV1().Secrets(namespace).Get(ctx, secretName, v1.GetOptions{})
 	if err != nil {
-		return "", err
+		return "", utils.Error{Description: fmt.Sprintf(utils.ErrFetchingSecretData, secretName, secretKey), BackendError: err.Error(), Code: getErrorCode(err), Err: err}
 	}
 
 	if secret.Data == nil {
-		return "", utils.Error{Description: fmt.Sprintf(utils.ErrEmptyDataInSecret, secretName)}
+		return "", utils.Error{Description: fmt.Sprintf(utils.ErrEmptyDataInSecret, secretName), Code: utils.CredentialNotFound}
 	}
 
 	byteData, ok := secret.Data[secretKey]
 	if !ok {
-		return "", utils.Error{Description: fmt.Sprintf(utils.ErrExpectedDataNotFound, secretKey, secretName)}
+		return "", utils.Error{Description: fmt.Sprintf(utils.ErrExpectedDataNotFound, secretKey, secretName), Code: utils.CredentialNotFound}
 	}
 
 	return strings.TrimSuffix(string(byteData), "\n"), nil
 }
+
+// getErrorCode classifies an error returned by the k8s apiserver, only a missing secret means the credentials
+// are not found, timeouts, server errors and throttling can be retried.
+func getErrorCode(err error) utils.ErrorCode {
+	switch {
+	case apierrors.IsNotFound(err):
+		return utils.CredentialNotFound
+	case apierrors.IsForbidden(err):
+		return utils.K8sForbidden
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err),
+		apierrors.IsInternalError(err), apierrors.IsServiceUnavailable(err), apierrors.IsUnexpectedServerError(err):
+		return utils.K8sUnavailable
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Code >= http.StatusInternalServerError {
+		return utils.K8sUnavailable
+	}
+	// The apiserver could not be reached
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return utils.K8sUnavailable
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s_utils
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestGetSecretDataErrorCodes(t *testing.T) {
+	secrets := schema.GroupResource{Resource: "secrets"}
+	testcases := []struct {
+		testcasename      string
+		getErr            error
+		expectedCode      utils.ErrorCode
+		retryable         bool
+		requiresUserInput bool
+	}{
+		{
+			testcasename:      "Secret not found",
+			getErr:            apierrors.NewNotFound(secrets, utils.STORAGE_SECRET_STORE_SECRET),
+			expectedCode:      utils.CredentialNotFound,
+			requiresUserInput: true,
+		},
+		{
+			testcasename:      "Forbidden",
+			getErr:            apierrors.NewForbidden(secrets, utils.STORAGE_SECRET_STORE_SECRET, errors.New("rbac")),
+			expectedCode:      utils.K8sForbidden,
+			requiresUserInput: true,
+		},
+		{
+			testcasename: "Timeout",
+			getErr:       apierrors.NewTimeoutError("timeout", 1),
+			expectedCode: utils.K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename: "Server timeout",
+			getErr:       apierrors.NewServerTimeout(secrets, "get", 1),
+			expectedCode: utils.K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename: "Too many requests",
+			getErr:       apierrors.NewTooManyRequests("throttled", 1),
+			expectedCode: utils.K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename: "Internal error",
+			getErr:       apierrors.NewInternalError(errors.New("internal")),
+			expectedCode: utils.K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename: "Service unavailable",
+			getErr:       apierrors.NewServiceUnavailable("unavailable"),
+			expectedCode: utils.K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename: "Bad gateway",
+			getErr:       apierrors.NewGenericServerResponse(502, "get", secrets, utils.STORAGE_SECRET_STORE_SECRET, "", 0, true),
+			expectedCode: utils.K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename: "apiserver unreachable",
+			getErr:       &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			expectedCode: utils.K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename: "Unclassified error",
+			getErr:       apierrors.NewBadRequest("bad request"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			clientset.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, testcase.getErr
+			})
+			kc := KubernetesClient{Namespace: "kube-system", Clientset: clientset}
+
+			_, err := GetSecretData(kc, utils.STORAGE_SECRET_STORE_SECRET, utils.SECRET_STORE_FILE)
+			assert.NotNil(t, err)
+			assert.True(t, errors.Is(err, testcase.getErr))
+			assert.Equal(t, testcase.expectedCode, utils.GetErrorCode(err))
+			assert.Equal(t, testcase.retryable, utils.IsRetryable(err))
+			assert.Equal(t, testcase.requiresUserInput, utils.RequiresUserAction(err))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics defines the metrics recorded by the authenticators while fetching tokens.
+package metrics
+
+import "time"
+
+// Labels identify the authenticator recording the metric.
+type Labels struct {
+	// AuthType is the type of credentials used to fetch the token (iam or pod-identity).
+	AuthType string
+	// Provider is the provider the authenticator was created for (vpc, bluemix or softlayer).
+	Provider string
+}
+
+// Recorder records the metrics of the authenticators.
+// Implementations must be safe for concurrent use, NoopRecorder is used if none is configured.
+type Recorder interface {
+	// CacheHit is called when GetToken returns the token in cache.
+	CacheHit(labels Labels)
+	// CacheMiss is called when GetToken has to fetch a token from IAM.
+	CacheMiss(labels Labels)
+	// ObserveIAMRequest is called after every token request made to IAM, err is nil if the request succeeded.
+	ObserveIAMRequest(labels Labels, endpoint string, duration time.Duration, err error)
+	// Retry is called for every token request retried.
+	Retry(labels Labels)
+	// Fallback is called when the private IAM endpoint timed out and the token is requested from the public endpoint.
+	Fallback(labels Labels)
+	// SetTokenLifetime is called with the remaining lifetime of the token returned by GetToken.
+	SetTokenLifetime(labels Labels, lifetime time.Duration)
+}
+
+// NoopRecorder ...
+type NoopRecorder struct{}
+
+// CacheHit ...
+func (NoopRecorder) CacheHit(labels Labels) {}
+
+// CacheMiss ...
+func (NoopRecorder) CacheMiss(labels Labels) {}
+
+// ObserveIAMRequest ...
+func (NoopRecorder) ObserveIAMRequest(labels Labels, endpoint string, duration time.Duration, err error) {
+}
+
+// Retry ...
+func (NoopRecorder) Retry(labels Labels) {}
+
+// Fallback ...
+func (NoopRecorder) Fallback(labels Labels) {}
+
+// SetTokenLifetime ...
+func (NoopRecorder) SetTokenLifetime(labels Labels, lifetime time.Duration) {}
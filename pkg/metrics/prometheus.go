@@ -0,0 +1,153 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "secret_utils"
+
+	labelAuthType = "auth_type"
+	labelProvider = "provider"
+	labelEndpoint = "endpoint"
+	labelResult   = "result"
+)
+
+// PrometheusRecorder is a Recorder exposing the metrics as prometheus collectors.
+type PrometheusRecorder struct {
+	cacheRequests  *prometheus.CounterVec
+	iamRequests    *prometheus.HistogramVec
+	retries        *prometheus.CounterVec
+	fallbacks      *prometheus.CounterVec
+	tokenLifetimes *prometheus.GaugeVec
+}
+
+// NewPrometheusRecorder creates the collectors and registers them with registerer,
+// prometheus.DefaultRegisterer is used if registerer is nil. If the collectors are already registered
+// (for example by the recorder of another provider), the registered collectors are reused.
+func NewPrometheusRecorder(registerer prometheus.Registerer) (*PrometheusRecorder, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	pr := &PrometheusRecorder{
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_cache_requests_total",
+			Help:      "Number of GetToken calls, by result (hit when the token in cache was returned, else miss).",
+		}, []string{labelAuthType, labelProvider, labelResult}),
+		iamRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "iam_request_duration_seconds",
+			Help:      "Latency of the token requests made to IAM, by endpoint and result (success or error).",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{labelAuthType, labelProvider, labelEndpoint, labelResult}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "iam_request_retries_total",
+			Help:      "Number of token requests retried.",
+		}, []string{labelAuthType, labelProvider}),
+		fallbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "iam_public_fallbacks_total",
+			Help:      "Number of times the private IAM endpoint timed out and the public endpoint was used.",
+		}, []string{labelAuthType, labelProvider}),
+		tokenLifetimes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "token_remaining_lifetime_seconds",
+			Help:      "Remaining lifetime of the last token returned by GetToken.",
+		}, []string{labelAuthType, labelProvider}),
+	}
+
+	var err error
+	var collector prometheus.Collector
+	if collector, err = register(registerer, pr.cacheRequests); err != nil {
+		return nil, err
+	}
+	pr.cacheRequests = collector.(*prometheus.CounterVec)
+	if collector, err = register(registerer, pr.iamRequests); err != nil {
+		return nil, err
+	}
+	pr.iamRequests = collector.(*prometheus.HistogramVec)
+	if collector, err = register(registerer, pr.retries); err != nil {
+		return nil, err
+	}
+	pr.retries = collector.(*prometheus.CounterVec)
+	if collector, err = register(registerer, pr.fallbacks); err != nil {
+		return nil, err
+	}
+	pr.fallbacks = collector.(*prometheus.CounterVec)
+	if collector, err = register(registerer, pr.tokenLifetimes); err != nil {
+		return nil, err
+	}
+	pr.tokenLifetimes = collector.(*prometheus.GaugeVec)
+	return pr, nil
+}
+
+// register registers collector with registerer, returning the collector already registered if any.
+// An error is returned if the collector registered is of another type.
+func register(registerer prometheus.Registerer, collector prometheus.Collector) (prometheus.Collector, error) {
+	err := registerer.Register(collector)
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) && reflect.TypeOf(alreadyRegistered.ExistingCollector) == reflect.TypeOf(collector) {
+		return alreadyRegistered.ExistingCollector, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// CacheHit ...
+func (pr *PrometheusRecorder) CacheHit(labels Labels) {
+	pr.cacheRequests.WithLabelValues(labels.AuthType, labels.Provider, "hit").Inc()
+}
+
+// CacheMiss ...
+func (pr *PrometheusRecorder) CacheMiss(labels Labels) {
+	pr.cacheRequests.WithLabelValues(labels.AuthType, labels.Provider, "miss").Inc()
+}
+
+// ObserveIAMRequest ...
+func (pr *PrometheusRecorder) ObserveIAMRequest(labels Labels, endpoint string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	pr.iamRequests.WithLabelValues(labels.AuthType, labels.Provider, endpoint, result).Observe(duration.Seconds())
+}
+
+// Retry ...
+func (pr *PrometheusRecorder) Retry(labels Labels) {
+	pr.retries.WithLabelValues(labels.AuthType, labels.Provider).Inc()
+}
+
+// Fallback ...
+func (pr *PrometheusRecorder) Fallback(labels Labels) {
+	pr.fallbacks.WithLabelValues(labels.AuthType, labels.Provider).Inc()
+}
+
+// SetTokenLifetime ...
+func (pr *PrometheusRecorder) SetTokenLifetime(labels Labels, lifetime time.Duration) {
+	pr.tokenLifetimes.WithLabelValues(labels.AuthType, labels.Provider).Set(lifetime.Seconds())
+}
@@ -0,0 +1,99 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	pr, err := NewPrometheusRecorder(registry)
+	assert.Nil(t, err)
+
+	labels := Labels{AuthType: "iam", Provider: "vpc"}
+	pr.CacheHit(labels)
+	pr.CacheHit(labels)
+	pr.CacheMiss(labels)
+	pr.ObserveIAMRequest(labels, "https://private.iam.cloud.ibm.com", time.Second, errors.New("timeout"))
+	pr.ObserveIAMRequest(labels, "https://iam.cloud.ibm.com", time.Second, nil)
+	pr.Retry(labels)
+	pr.Fallback(labels)
+	pr.SetTokenLifetime(labels, time.Hour)
+
+	expected := `
+# HELP secret_utils_token_cache_requests_total Number of GetToken calls, by result (hit when the token in cache was returned, else miss).
+# TYPE secret_utils_token_cache_requests_total counter
+secret_utils_token_cache_requests_total{auth_type="iam",provider="vpc",result="hit"} 2
+secret_utils_token_cache_requests_total{auth_type="iam",provider="vpc",result="miss"} 1
+# HELP secret_utils_iam_request_retries_total Number of token requests retried.
+# TYPE secret_utils_iam_request_retries_total counter
+secret_utils_iam_request_retries_total{auth_type="iam",provider="vpc"} 1
+# HELP secret_utils_iam_public_fallbacks_total Number of times the private IAM endpoint timed out and the public endpoint was used.
+# TYPE secret_utils_iam_public_fallbacks_total counter
+secret_utils_iam_public_fallbacks_total{auth_type="iam",provider="vpc"} 1
+# HELP secret_utils_token_remaining_lifetime_seconds Remaining lifetime of the last token returned by GetToken.
+# TYPE secret_utils_token_remaining_lifetime_seconds gauge
+secret_utils_token_remaining_lifetime_seconds{auth_type="iam",provider="vpc"} 3600
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"secret_utils_token_cache_requests_total", "secret_utils_iam_request_retries_total",
+		"secret_utils_iam_public_fallbacks_total", "secret_utils_token_remaining_lifetime_seconds"))
+
+	count, err := testutil.GatherAndCount(registry, "secret_utils_iam_request_duration_seconds")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	// A second recorder (for another provider) reuses the collectors registered.
+	other, err := NewPrometheusRecorder(registry)
+	assert.Nil(t, err)
+	other.CacheHit(labels)
+	other.CacheMiss(Labels{AuthType: "iam", Provider: "bluemix"})
+	expected = `
+# HELP secret_utils_token_cache_requests_total Number of GetToken calls, by result (hit when the token in cache was returned, else miss).
+# TYPE secret_utils_token_cache_requests_total counter
+secret_utils_token_cache_requests_total{auth_type="iam",provider="vpc",result="hit"} 3
+secret_utils_token_cache_requests_total{auth_type="iam",provider="vpc",result="miss"} 1
+secret_utils_token_cache_requests_total{auth_type="iam",provider="bluemix",result="miss"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "secret_utils_token_cache_requests_total"))
+
+	// Registering a different collector under the same name fails.
+	registry = prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_cache_requests_total",
+		Help:      "Number of GetToken calls, by result (hit when the token in cache was returned, else miss).",
+	}, []string{labelAuthType, labelProvider}))
+	_, err = NewPrometheusRecorder(registry)
+	assert.NotNil(t, err)
+}
+
+func TestPrometheusRecorderDefaultRegisterer(t *testing.T) {
+	first, err := NewPrometheusRecorder(nil)
+	assert.Nil(t, err)
+	second, err := NewPrometheusRecorder(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, first.cacheRequests, second.cacheRequests)
+}
@@ -0,0 +1,108 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package token
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// IAMClaims are the claims of an IBM Cloud IAM access token.
+type IAMClaims struct {
+	// IAMID is the IAM ID of the identity the token was issued to, for example iam-ServiceId-<uuid> or iam-Profile-<uuid>.
+	IAMID string `json:"iam_id"`
+	// SubType is the type of the subject, for example ServiceId, Profile or Person.
+	SubType string `json:"sub_type"`
+	// Account is the account the token is scoped to.
+	Account IAMAccount `json:"account"`
+	// Scope is the space separated list of scopes, see Scopes.
+	Scope string `json:"scope"`
+	// GrantType is the grant used to fetch the token, for example urn:ibm:params:oauth:grant-type:apikey.
+	GrantType string `json:"grant_type"`
+	// ClientID is the client the token was issued for.
+	ClientID string `json:"client_id"`
+
+	// RegisteredClaims carries sub, iss, iat, exp and nbf.
+	jwt.RegisteredClaims
+}
+
+// IAMAccount ...
+type IAMAccount struct {
+	// BSS is the ID of the account.
+	BSS string `json:"bss"`
+	// IMS is the ID of the linked classic infrastructure account, if any.
+	IMS string `json:"ims"`
+	// Valid is false if the account is not active.
+	Valid bool `json:"valid"`
+}
+
+// AccountID returns the ID of the account the token is scoped to.
+func (claims IAMClaims) AccountID() string {
+	return claims.Account.BSS
+}
+
+// Scopes ...
+func (claims IAMClaims) Scopes() []string {
+	return strings.Fields(claims.Scope)
+}
+
+// IssuedAt returns the time the token was issued at, zero if the token does not carry iat.
+func (claims IAMClaims) IssuedAt() time.Time {
+	if claims.RegisteredClaims.IssuedAt == nil {
+		return time.Time{}
+	}
+	return claims.RegisteredClaims.IssuedAt.Time
+}
+
+// ExpiresAt returns the time the token expires at, zero if the token does not carry exp.
+func (claims IAMClaims) ExpiresAt() time.Time {
+	if claims.RegisteredClaims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.RegisteredClaims.ExpiresAt.Time
+}
+
+// ParseIAMClaimsUnverified parses the claims of the token without verifying its signature or its lifetime.
+// It must only be used on tokens received from IAM over TLS, for example the token cached by an authenticator.
+func ParseIAMClaimsUnverified(tokenString string) (*IAMClaims, error) {
+	if tokenString == "" {
+		return nil, errors.New("empty token string")
+	}
+
+	claims := new(IAMClaims)
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseIAMClaims parses the claims of the token, verifying its signature using the key returned by keyFunc,
+// and its exp, iat and nbf claims. options are passed to the jwt parser, for example to restrict the signing methods.
+func ParseIAMClaims(tokenString string, keyFunc jwt.Keyfunc, options ...jwt.ParserOption) (*IAMClaims, error) {
+	if tokenString == "" {
+		return nil, errors.New("empty token string")
+	}
+
+	claims := new(IAMClaims)
+	if _, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, options...); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSigningKey = []byte("signing-key")
+
+// newSignedToken returns a HS256 token signed using testSigningKey, issued at iat and expiring at exp.
+func newSignedToken(t *testing.T, iat, exp time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iam_id":     "iam-ServiceId-1234",
+		"sub":        "ServiceId-1234",
+		"sub_type":   "ServiceId",
+		"account":    map[string]interface{}{"bss": "account-1234", "ims": "2345", "valid": true},
+		"scope":      "ibm openid",
+		"grant_type": "urn:ibm:params:oauth:grant-type:apikey",
+		"client_id":  "default",
+		"iss":        "https://iam.cloud.ibm.com/identity",
+		"iat":        iat.Unix(),
+		"exp":        exp.Unix(),
+	}).SignedString(testSigningKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token, error: %v", err)
+	}
+	return token
+}
+
+func TestParseIAMClaims(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	validToken := newSignedToken(t, now, now.Add(time.Hour))
+	expiredToken := newSignedToken(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	keyFunc := func(*jwt.Token) (interface{}, error) { return testSigningKey, nil }
+	wrongKeyFunc := func(*jwt.Token) (interface{}, error) { return []byte("wrong-key"), nil }
+
+	testcases := []struct {
+		testcasename   string
+		token          string
+		verified       bool
+		keyFunc        jwt.Keyfunc
+		expectedIssued time.Time
+		expectError    bool
+	}{
+		{
+			testcasename:   "Unverified",
+			token:          validToken,
+			expectedIssued: now,
+		},
+		{
+			testcasename:   "Unverified expired token",
+			token:          expiredToken,
+			expectedIssued: now.Add(-2 * time.Hour),
+		},
+		{
+			testcasename: "Unverified empty token",
+			token:        "",
+			expectError:  true,
+		},
+		{
+			testcasename: "Unverified invalid token",
+			token:        "Invalid",
+			expectError:  true,
+		},
+		{
+			testcasename:   "Verified",
+			token:          validToken,
+			verified:       true,
+			keyFunc:        keyFunc,
+			expectedIssued: now,
+		},
+		{
+			testcasename: "Verified with wrong key",
+			token:        validToken,
+			verified:     true,
+			keyFunc:      wrongKeyFunc,
+			expectError:  true,
+		},
+		{
+			testcasename: "Verified expired token",
+			token:        expiredToken,
+			verified:     true,
+			keyFunc:      keyFunc,
+			expectError:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			var claims *IAMClaims
+			var err error
+			if testcase.verified {
+				claims, err = ParseIAMClaims(testcase.token, testcase.keyFunc)
+			} else {
+				claims, err = ParseIAMClaimsUnverified(testcase.token)
+			}
+
+			assert.Equal(t, testcase.expectError, err != nil)
+			if testcase.expectError {
+				return
+			}
+			assert.Equal(t, "iam-ServiceId-1234", claims.IAMID)
+			assert.Equal(t, "ServiceId-1234", claims.Subject)
+			assert.Equal(t, "ServiceId", claims.SubType)
+			assert.Equal(t, "account-1234", claims.AccountID())
+			assert.Equal(t, IAMAccount{BSS: "account-1234", IMS: "2345", Valid: true}, claims.Account)
+			assert.Equal(t, []string{"ibm", "openid"}, claims.Scopes())
+			assert.Equal(t, "urn:ibm:params:oauth:grant-type:apikey", claims.GrantType)
+			assert.Equal(t, "https://iam.cloud.ibm.com/identity", claims.Issuer)
+			assert.True(t, testcase.expectedIssued.Equal(claims.IssuedAt()))
+			assert.True(t, testcase.expectedIssued.Add(time.Hour).Equal(claims.ExpiresAt()))
+		})
+	}
+}
+
+func TestIAMClaimsWithoutTimes(t *testing.T) {
+	claims := IAMClaims{}
+	assert.True(t, claims.IssuedAt().IsZero())
+	assert.True(t, claims.ExpiresAt().IsZero())
+	assert.Empty(t, claims.Scopes())
+}
@@ -24,9 +24,35 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// LifetimePolicy decides the minimum remaining lifetime of a token for it to be used.
+type LifetimePolicy struct {
+	// MinRemaining is the minimum remaining lifetime, it takes precedence over MinRemainingPercent if set.
+	MinRemaining time.Duration
+	// MinRemainingPercent is the minimum remaining lifetime as a percentage (0 - 99) of the total lifetime
+	// of the token (exp - iat). It is not applied to tokens without iat.
+	MinRemainingPercent int
+}
+
+// minRemaining returns the minimum remaining lifetime in seconds, for a token issued at iat and expiring at exp.
+func (policy LifetimePolicy) minRemaining(iat, exp uint64) uint64 {
+	if policy.MinRemaining > 0 {
+		return uint64(policy.MinRemaining / time.Second)
+	}
+	if iat == 0 || exp <= iat {
+		return 0
+	}
+	return (exp - iat) * uint64(policy.MinRemainingPercent) / 100
+}
+
 // CheckTokenLifeTime checks whether the lifetime of token is valid or not
-// and returns life time of the token
+// and returns life time of the token. The token must have at least utils.TokenExpirydiff seconds remaining.
 func CheckTokenLifeTime(tokenString string) (uint64, error) {
+	return CheckTokenLifeTimeWithPolicy(tokenString, LifetimePolicy{MinRemaining: time.Duration(utils.TokenExpirydiff) * time.Second})
+}
+
+// CheckTokenLifeTimeWithPolicy checks whether the lifetime of token is valid or not
+// and returns life time of the token. The token must have the remaining lifetime required by policy.
+func CheckTokenLifeTimeWithPolicy(tokenString string, policy LifetimePolicy) (uint64, error) {
 	var tokenLifeTime uint64
 
 	token, err := parseToken(tokenString)
@@ -43,8 +69,9 @@ func CheckTokenLifeTime(tokenString string) (uint64, error) {
 		if expiryTime, ok = claims["exp"]; !ok {
 			return tokenLifeTime, errors.New("unable to find expiry time of token")
 		}
+		issuedAt, _ := claims["iat"].(float64)
 		tokenLifeTime = uint64(expiryTime.(float64)) - uint64(currentTime)
-		if tokenLifeTime < utils.TokenExpirydiff {
+		if tokenLifeTime < policy.minRemaining(uint64(issuedAt), uint64(expiryTime.(float64))) {
 			return tokenLifeTime, errors.New("token life time is less than expected value")
 		}
 		return tokenLifeTime, nil
@@ -52,6 +79,15 @@ func CheckTokenLifeTime(tokenString string) (uint64, error) {
 	return tokenLifeTime, errors.New("unable to fetch token claims")
 }
 
+// GetTokenSubject returns the subject (sub) and IAM ID (iam_id) claims of the token, without verifying it.
+func GetTokenSubject(tokenString string) (subject, iamID string, err error) {
+	claims, err := ParseIAMClaimsUnverified(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.Subject, claims.IAMID, nil
+}
+
 // parseToken parses token string to jwt token
 func parseToken(tokenString string) (*jwt.Token, error) {
 	if tokenString == "" {
@@ -0,0 +1,222 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package token
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// keysPath is the path of IAM's JWKS endpoint.
+	keysPath = "/identity/keys"
+	// issuerPath is appended to the public IAM host to build the expected iss claim.
+	issuerPath = "/identity"
+	// privateHostPrefix is the prefix of the private IAM hosts, tokens fetched from them are issued by the public host.
+	privateHostPrefix = "private."
+
+	// DefaultKeysCacheTTL is how long the keys fetched from IAM are used before being fetched again.
+	DefaultKeysCacheTTL = time.Hour
+	// DefaultKeysMinRefreshInterval is the minimum time between two fetches of the keys,
+	// when a token signed with an unknown key is received.
+	DefaultKeysMinRefreshInterval = 30 * time.Second
+)
+
+// VerifierOptions ...
+type VerifierOptions struct {
+	// HTTPClient is used to fetch the keys, defaults to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+	// Issuer is the expected iss claim, defaults to https://<public IAM host>/identity.
+	Issuer string
+	// CacheTTL defaults to DefaultKeysCacheTTL.
+	CacheTTL time.Duration
+	// MinRefreshInterval defaults to DefaultKeysMinRefreshInterval.
+	MinRefreshInterval time.Duration
+}
+
+// Verifier verifies IAM tokens using the public keys of IAM, fetched from the JWKS endpoint of the token exchange URL.
+// The keys are cached, and fetched again when they expire or when a token is signed with a key not in cache (key rotation).
+type Verifier struct {
+	keysURL            string
+	issuer             string
+	httpClient         *http.Client
+	cacheTTL           time.Duration
+	minRefreshInterval time.Duration
+
+	mutex       sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// jwks is the JSON Web Key Set returned by IAM.
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk ...
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// NewVerifier returns a verifier for the tokens issued by the IAM of tokenExchangeURL, for example
+// https://private.iam.cloud.ibm.com/identity/token. The keys are fetched from <scheme>://<host>/identity/keys.
+func NewVerifier(tokenExchangeURL string, optionalArgs ...VerifierOptions) (*Verifier, error) {
+	u, err := url.Parse(tokenExchangeURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, utils.Error{Description: fmt.Sprintf(utils.ErrInvalidTokenExchangeURL, tokenExchangeURL), BackendError: fmt.Sprint(err), Err: err}
+	}
+
+	var opts VerifierOptions
+	if len(optionalArgs) != 0 {
+		opts = optionalArgs[0]
+	}
+
+	v := &Verifier{
+		keysURL:            u.Scheme + "://" + u.Host + keysPath,
+		issuer:             opts.Issuer,
+		httpClient:         opts.HTTPClient,
+		cacheTTL:           opts.CacheTTL,
+		minRefreshInterval: opts.MinRefreshInterval,
+	}
+	if v.issuer == "" {
+		v.issuer = u.Scheme + "://" + strings.TrimPrefix(u.Host, privateHostPrefix) + issuerPath
+	}
+	if v.httpClient == nil {
+		v.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if v.cacheTTL == 0 {
+		v.cacheTTL = DefaultKeysCacheTTL
+	}
+	if v.minRefreshInterval == 0 {
+		v.minRefreshInterval = DefaultKeysMinRefreshInterval
+	}
+	return v, nil
+}
+
+// Verify verifies the RS256 signature of the token using IAM's public keys, that it was issued by IAM,
+// has not expired and is not used before its nbf time, and returns its claims.
+func (v *Verifier) Verify(tokenString string) (*IAMClaims, error) {
+	claims, err := ParseIAMClaims(tokenString, v.keyFunc, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	if err != nil {
+		return nil, utils.Error{Description: utils.ErrVerifyingToken, BackendError: err.Error(), Err: err}
+	}
+
+	// The jwt parser accepts tokens without exp, IAM tokens always carry it.
+	if !claims.VerifyExpiresAt(time.Now(), true) {
+		return nil, utils.Error{Description: utils.ErrVerifyingToken, BackendError: "token has no expiry time"}
+	}
+	if !claims.VerifyIssuer(v.issuer, true) {
+		return nil, utils.Error{Description: utils.ErrVerifyingToken, BackendError: fmt.Sprintf("unexpected issuer %q, expected %q", claims.Issuer, v.issuer)}
+	}
+	return claims, nil
+}
+
+// keyFunc returns the public key matching the kid of the token, fetching the keys if not in cache.
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	key, ok := v.keys[kid]
+	if ok && time.Since(v.fetchedAt) <= v.cacheTTL {
+		return key, nil
+	}
+
+	// The kid is unknown (IAM rotated its keys) or the keys expired, fetch them again unless they were fetched just now.
+	// If IAM cannot be reached, the expired key is used.
+	if time.Since(v.lastAttempt) >= v.minRefreshInterval {
+		err := v.refreshKeys()
+		if err == nil {
+			key, ok = v.keys[kid]
+		} else if !ok {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %s", kid)
+	}
+	return key, nil
+}
+
+// refreshKeys fetches the keys from IAM, the caller must hold the mutex.
+func (v *Verifier) refreshKeys() error {
+	v.lastAttempt = time.Now()
+	resp, err := v.httpClient.Get(v.keysURL)
+	if err != nil {
+		return utils.Error{Description: fmt.Sprintf(utils.ErrFetchingJWKS, v.keysURL), BackendError: err.Error(), Code: utils.IAMUnavailable, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return utils.Error{Description: fmt.Sprintf(utils.ErrFetchingJWKS, v.keysURL), BackendError: fmt.Sprintf("status code: %d", resp.StatusCode), Code: utils.IAMUnavailable}
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return utils.Error{Description: fmt.Sprintf(utils.ErrFetchingJWKS, v.keysURL), BackendError: err.Error(), Code: utils.IAMUnavailable, Err: err}
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	return nil
+}
+
+// rsaPublicKey decodes the modulus and exponent of the key.
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
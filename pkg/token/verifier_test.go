@@ -0,0 +1,281 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/secret-utils-lib/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// testJWKS serves the public keys set, counting the requests.
+type testJWKS struct {
+	mutex    sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests int32
+	status   int
+}
+
+func (tj *testJWKS) setKeys(keys map[string]*rsa.PrivateKey) {
+	tj.mutex.Lock()
+	defer tj.mutex.Unlock()
+	tj.keys = keys
+}
+
+func (tj *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&tj.requests, 1)
+	if r.URL.Path != "/identity/keys" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	tj.mutex.Lock()
+	defer tj.mutex.Unlock()
+	if tj.status != 0 {
+		w.WriteHeader(tj.status)
+		return
+	}
+
+	set := jwks{}
+	for kid, key := range tj.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(set)
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key, error: %v", err)
+	}
+	return key
+}
+
+// newRSAToken returns a token signed using key, with the kid header and the claims given added to the default IAM claims.
+func newRSAToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	now := time.Now()
+	mapClaims := jwt.MapClaims{
+		"iam_id":  "iam-ServiceId-1234",
+		"sub":     "ServiceId-1234",
+		"account": map[string]interface{}{"bss": "account-1234"},
+		"iss":     "https://iam.cloud.ibm.com/identity",
+		"iat":     now.Unix(),
+		"exp":     now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(mapClaims, name)
+			continue
+		}
+		mapClaims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, mapClaims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token, error: %v", err)
+	}
+	return signed
+}
+
+func TestNewVerifier(t *testing.T) {
+	testcases := []struct {
+		testcasename     string
+		tokenExchangeURL string
+		expectedKeysURL  string
+		expectedIssuer   string
+		expectError      bool
+	}{
+		{
+			testcasename:     "Private prod IAM",
+			tokenExchangeURL: "https://private.iam.cloud.ibm.com/identity/token",
+			expectedKeysURL:  "https://private.iam.cloud.ibm.com/identity/keys",
+			expectedIssuer:   "https://iam.cloud.ibm.com/identity",
+		},
+		{
+			testcasename:     "Public stage IAM without path",
+			tokenExchangeURL: "https://iam.test.cloud.ibm.com",
+			expectedKeysURL:  "https://iam.test.cloud.ibm.com/identity/keys",
+			expectedIssuer:   "https://iam.test.cloud.ibm.com/identity",
+		},
+		{
+			testcasename:     "Relative URL",
+			tokenExchangeURL: "iam.cloud.ibm.com/identity/token",
+			expectError:      true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			v, err := NewVerifier(testcase.tokenExchangeURL)
+			assert.Equal(t, testcase.expectError, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, testcase.expectedKeysURL, v.keysURL)
+			assert.Equal(t, testcase.expectedIssuer, v.issuer)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key1, key2, unknownKey := newRSAKey(t), newRSAKey(t), newRSAKey(t)
+	jwksServer := &testJWKS{keys: map[string]*rsa.PrivateKey{"key-1": key1}}
+	server := httptest.NewServer(jwksServer)
+	defer server.Close()
+
+	v, err := NewVerifier(server.URL+"/identity/token", VerifierOptions{Issuer: "https://iam.cloud.ibm.com/identity", MinRefreshInterval: time.Nanosecond})
+	assert.Nil(t, err)
+
+	hsToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": "https://iam.cloud.ibm.com/identity",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	assert.Nil(t, err)
+
+	testcases := []struct {
+		testcasename string
+		token        string
+		expectError  bool
+	}{
+		{
+			testcasename: "Valid token",
+			token:        newRSAToken(t, key1, "key-1", nil),
+		},
+		{
+			testcasename: "Signed using another key with the same kid",
+			token:        newRSAToken(t, unknownKey, "key-1", nil),
+			expectError:  true,
+		},
+		{
+			testcasename: "Unknown kid",
+			token:        newRSAToken(t, unknownKey, "key-3", nil),
+			expectError:  true,
+		},
+		{
+			testcasename: "Wrong issuer",
+			token:        newRSAToken(t, key1, "key-1", jwt.MapClaims{"iss": "https://iam.example.com/identity"}),
+			expectError:  true,
+		},
+		{
+			testcasename: "Expired",
+			token:        newRSAToken(t, key1, "key-1", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+			expectError:  true,
+		},
+		{
+			testcasename: "Without expiry",
+			token:        newRSAToken(t, key1, "key-1", jwt.MapClaims{"exp": nil}),
+			expectError:  true,
+		},
+		{
+			testcasename: "Not valid yet",
+			token:        newRSAToken(t, key1, "key-1", jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()}),
+			expectError:  true,
+		},
+		{
+			testcasename: "HS256 token",
+			token:        hsToken,
+			expectError:  true,
+		},
+		{
+			testcasename: "Invalid token",
+			token:        "Invalid",
+			expectError:  true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			claims, err := v.Verify(testcase.token)
+			assert.Equal(t, testcase.expectError, err != nil)
+			if err != nil {
+				var verifyErr utils.Error
+				assert.True(t, errors.As(err, &verifyErr))
+				assert.Equal(t, utils.ErrVerifyingToken, verifyErr.Description)
+				return
+			}
+			assert.Equal(t, "iam-ServiceId-1234", claims.IAMID)
+			assert.Equal(t, "account-1234", claims.AccountID())
+		})
+	}
+
+	// Rotating the keys: a token signed using the new key triggers a fetch of the keys.
+	jwksServer.setKeys(map[string]*rsa.PrivateKey{"key-2": key2})
+	requests := atomic.LoadInt32(&jwksServer.requests)
+	_, err = v.Verify(newRSAToken(t, key2, "key-2", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, requests+1, atomic.LoadInt32(&jwksServer.requests))
+
+	// The keys are cached.
+	_, err = v.Verify(newRSAToken(t, key2, "key-2", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, requests+1, atomic.LoadInt32(&jwksServer.requests))
+}
+
+func TestVerifyKeysCache(t *testing.T) {
+	key := newRSAKey(t)
+	jwksServer := &testJWKS{keys: map[string]*rsa.PrivateKey{"key-1": key}}
+	server := httptest.NewServer(jwksServer)
+	defer server.Close()
+
+	v, err := NewVerifier(server.URL, VerifierOptions{Issuer: "https://iam.cloud.ibm.com/identity", CacheTTL: time.Nanosecond, MinRefreshInterval: time.Hour})
+	assert.Nil(t, err)
+
+	token := newRSAToken(t, key, "key-1", nil)
+	_, err = v.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&jwksServer.requests))
+
+	// Unknown kids do not fetch the keys again before the minimum refresh interval.
+	_, err = v.Verify(newRSAToken(t, key, "key-2", nil))
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&jwksServer.requests))
+
+	// When IAM cannot be reached, the expired key is used.
+	v.minRefreshInterval = time.Nanosecond
+	jwksServer.mutex.Lock()
+	jwksServer.status = http.StatusServiceUnavailable
+	jwksServer.mutex.Unlock()
+	_, err = v.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&jwksServer.requests))
+
+	// Without a cached key, the error fetching the keys is returned.
+	_, err = v.Verify(newRSAToken(t, key, "key-2", nil))
+	assert.True(t, errors.Is(err, utils.ErrIAMUnavailable))
+}
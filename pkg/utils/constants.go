@@ -41,6 +41,10 @@ const (
 	CLOUD_PROVIDER_ENV = "ibm-credentials.env"
 	// SECRET_STORE_FILE ...
 	SECRET_STORE_FILE = "slclient.toml"
+	// SECRET_CONFIG_PATH is the environment variable pointing to a local slclient.toml, used outside the cluster
+	SECRET_CONFIG_PATH = "SECRET_CONFIG_PATH"
+	// ENV_CREDENTIALS refers to credentials read from environment variables
+	ENV_CREDENTIALS = "environment"
 	// StagePrivateIAMURL ...
 	StagePrivateIAMURL = "https://private.iam.test.cloud.ibm.com"
 	// ProdPrivateIAMURL ...
@@ -57,6 +61,8 @@ const (
 	Softlayer = "softlayer"
 	// SatelliteProvider ...
 	SatelliteProvider = "upi"
+	// IPIProvider refers to installer provisioned (self managed) OpenShift clusters on IBM Cloud ...
+	IPIProvider = "ipi"
 	// VPCGen2 refers to VPC cluster type ...
 	VPCGen2 = "vpc-gen2_cruiser"
 	// Cruiser refers to the Classic cluster type ...
@@ -16,13 +16,121 @@
 
 package utils
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode is a machine readable classification of an Error.
+type ErrorCode string
+
+const (
+	// CredentialNotFound - the secret (or the key in it) holding the credentials could not be read.
+	CredentialNotFound ErrorCode = "CredentialNotFound"
+
+	// InvalidCredentials - the credentials were read but are empty or not in the expected format.
+	InvalidCredentials ErrorCode = "InvalidCredentials"
+
+	// IAMUnavailable - IAM could not be reached or failed to serve the request, the request can be retried.
+	IAMUnavailable ErrorCode = "IAMUnavailable"
+
+	// IAMRejected - IAM rejected the credentials, retrying without changing the credentials does not help.
+	IAMRejected ErrorCode = "IAMRejected"
+
+	// ConfigParse - a config (slclient.toml, cloud-conf, cluster-info) could not be parsed.
+	ConfigParse ErrorCode = "ConfigParse"
+
+	// InvalidConfig - the configuration passed to the library (for example authenticator options) is invalid.
+	InvalidConfig ErrorCode = "InvalidConfig"
+
+	// K8sUnavailable - the k8s apiserver could not be reached or failed to serve the request, the request can be retried.
+	K8sUnavailable ErrorCode = "K8sUnavailable"
+
+	// K8sForbidden - the service account is not allowed (RBAC) to read the secret or config map.
+	K8sForbidden ErrorCode = "K8sForbidden"
+)
+
+// Sentinel errors to be used with errors.Is, an Error matches the sentinel having the same code.
+var (
+	// ErrCredentialNotFound ...
+	ErrCredentialNotFound = Error{Code: CredentialNotFound}
+
+	// ErrInvalidCredentials ...
+	ErrInvalidCredentials = Error{Code: InvalidCredentials}
+
+	// ErrIAMUnavailable ...
+	ErrIAMUnavailable = Error{Code: IAMUnavailable}
+
+	// ErrIAMRejected ...
+	ErrIAMRejected = Error{Code: IAMRejected}
+
+	// ErrConfigParse ...
+	ErrConfigParse = Error{Code: ConfigParse}
+
+	// ErrInvalidConfig ...
+	ErrInvalidConfig = Error{Code: InvalidConfig}
+
+	// ErrK8sUnavailable ...
+	ErrK8sUnavailable = Error{Code: K8sUnavailable}
+
+	// ErrK8sForbidden ...
+	ErrK8sForbidden = Error{Code: K8sForbidden}
+)
 
 // Error is structure that is defined to locally to represent any error and it implements golang error
 type Error struct {
 	Description  string
 	BackendError string
 	Action       string
+	// Code classifies the error, it is empty for errors which are not classified.
+	Code ErrorCode
+	// Err is the underlying error, BackendError holds its message.
+	Err error
+}
+
+// Unwrap returns the underlying error, so that errors.Is and errors.As can reach the go-sdk-core and k8s errors.
+func (err Error) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether target is the sentinel error for the code of err.
+func (err Error) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.Code != "" && t.Description == "" && t.Code == err.Code
+}
+
+// GetErrorCode returns the code of the first classified Error in the chain of err, empty if there is none.
+func GetErrorCode(err error) ErrorCode {
+	for err != nil {
+		var e Error
+		if !errors.As(err, &e) {
+			return ""
+		}
+		if e.Code != "" {
+			return e.Code
+		}
+		err = e.Err
+	}
+	return ""
+}
+
+// IsRetryable reports whether the operation which failed with err can be retried as is.
+func IsRetryable(err error) bool {
+	switch GetErrorCode(err) {
+	case IAMUnavailable, K8sUnavailable:
+		return true
+	}
+	return false
+}
+
+// RequiresUserAction reports whether err is caused by missing or invalid credentials or config,
+// such errors need to be fixed in the cluster (hence alerted on) and operations depending on them failed.
+func RequiresUserAction(err error) bool {
+	switch GetErrorCode(err) {
+	case CredentialNotFound, InvalidCredentials, IAMRejected, ConfigParse, InvalidConfig, K8sForbidden:
+		return true
+	}
+	return false
 }
 
 // Error method implements the Error method golang error.
@@ -71,6 +179,21 @@ const (
 	// ErrSecretConfigPathUndefined ...
 	ErrSecretConfigPathUndefined = "SECRET_CONFIG_PATH is not defined"
 
+	// ErrReadingSecretConfig ...
+	ErrReadingSecretConfig = "Unable to read storage secret store config from %s"
+
+	// ErrK8sClientUndefined ...
+	ErrK8sClientUndefined = "k8s client is not initialized"
+
+	// ErrNoCachedToken ...
+	ErrNoCachedToken = "No token in cache, call GetToken to fetch a token"
+
+	// ErrParsingTokenClaims ...
+	ErrParsingTokenClaims = "Error parsing the claims of the token"
+
+	// ErrCachedAuthFailure ...
+	ErrCachedAuthFailure = "IAM rejected the secret recently, not retrying until the secret is updated or the failure cache expires"
+
 	// ErrEmptyTokenResponse ...
 	ErrEmptyTokenResponse = "Empty token response received"
 
@@ -98,6 +221,9 @@ const (
 	// ErrParsingConfig ...
 	ErrParsingConfig = "Failed to parse storage secret store config"
 
+	// ErrUnknownConfigKeys ...
+	ErrUnknownConfigKeys = "Storage secret store config has unknown keys"
+
 	// ErrFetchingENV ...
 	ErrFetchingENV = "Failed to gather environment variables"
 
@@ -113,6 +239,45 @@ const (
 	// ErrInvalidProviderType ...
 	ErrInvalidProviderType = "Invalid provider type. Valid options are - vpc, bluemix, softlayer"
 
+	// ErrUnknownCredentialFormat ...
+	ErrUnknownCredentialFormat = "Unknown credential source format: %s. Valid options are - ibm-credentials, secret-store, api-key"
+
+	// ErrInvalidOptions ...
+	ErrInvalidOptions = "Invalid options provided to initialize the authenticator"
+
+	// ErrCredentialSourcesNotFound ...
+	ErrCredentialSourcesNotFound = "Unable to initialize authenticator using any of the credential sources"
+
+	// ErrFetchingConfigMap ...
+	ErrFetchingConfigMap = "Unable to fetch config map %s"
+
 	// ErrEmptyConfigMapData ...
 	ErrEmptyConfigMapData = "Unable to find %s key in %s config map"
+
+	// ErrParsingCloudConf ...
+	ErrParsingCloudConf = "Failed to parse cloud-conf config map"
+
+	// ErrInvalidCloudConf ...
+	ErrInvalidCloudConf = "Invalid cloud-conf config map"
+
+	// ErrUnknownRegion ...
+	ErrUnknownRegion = "Unknown region %q, endpoints cannot be derived. Known regions are listed by config.KnownRegions"
+
+	// ErrDerivingEndpoints ...
+	ErrDerivingEndpoints = "Endpoints cannot be derived for the %s environment, they must be set in cloud-conf or slclient.toml"
+
+	// ErrInvalidCRN ...
+	ErrInvalidCRN = "Invalid CRN %q"
+
+	// ErrInvalidEndpoint ...
+	ErrInvalidEndpoint = "Invalid endpoint %q"
+
+	// ErrInvalidTokenExchangeURL ...
+	ErrInvalidTokenExchangeURL = "Invalid token exchange URL %s"
+
+	// ErrFetchingJWKS ...
+	ErrFetchingJWKS = "Unable to fetch IAM public keys from %s"
+
+	// ErrVerifyingToken ...
+	ErrVerifyingToken = "Token verification failed"
 )
@@ -18,6 +18,8 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,3 +54,75 @@ func TestError(t *testing.T) {
 	}
 
 }
+
+func TestErrorCodes(t *testing.T) {
+	backendErr := errors.New("backend error")
+	testcases := []struct {
+		testcasename      string
+		err               error
+		sentinel          error
+		expectedCode      ErrorCode
+		retryable         bool
+		requiresUserInput bool
+	}{
+		{
+			testcasename:      "Credential not found",
+			err:               Error{Description: "description", Code: CredentialNotFound, Err: backendErr},
+			sentinel:          ErrCredentialNotFound,
+			expectedCode:      CredentialNotFound,
+			requiresUserInput: true,
+		},
+		{
+			testcasename: "IAM unavailable wrapped by another error",
+			err:          fmt.Errorf("wrapped: %w", Error{Description: "description", Code: IAMUnavailable, Err: backendErr}),
+			sentinel:     ErrIAMUnavailable,
+			expectedCode: IAMUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename:      "Code of the wrapped error",
+			err:               Error{Description: "description", Err: Error{Code: IAMRejected, Err: backendErr}},
+			sentinel:          ErrIAMRejected,
+			expectedCode:      IAMRejected,
+			requiresUserInput: true,
+		},
+		{
+			testcasename:      "Invalid config",
+			err:               Error{Description: "description", Code: InvalidConfig, Err: backendErr},
+			sentinel:          ErrInvalidConfig,
+			expectedCode:      InvalidConfig,
+			requiresUserInput: true,
+		},
+		{
+			testcasename: "k8s apiserver unavailable",
+			err:          Error{Description: "description", Code: K8sUnavailable, Err: backendErr},
+			sentinel:     ErrK8sUnavailable,
+			expectedCode: K8sUnavailable,
+			retryable:    true,
+		},
+		{
+			testcasename:      "k8s access forbidden",
+			err:               Error{Description: "description", Code: K8sForbidden, Err: backendErr},
+			sentinel:          ErrK8sForbidden,
+			expectedCode:      K8sForbidden,
+			requiresUserInput: true,
+		},
+		{
+			testcasename: "Error without code",
+			err:          Error{Description: "description", Err: backendErr},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.testcasename, func(t *testing.T) {
+			assert.True(t, errors.Is(testcase.err, backendErr))
+			if testcase.sentinel != nil {
+				assert.True(t, errors.Is(testcase.err, testcase.sentinel))
+			}
+			assert.False(t, errors.Is(testcase.err, ErrConfigParse))
+			assert.Equal(t, testcase.expectedCode, GetErrorCode(testcase.err))
+			assert.Equal(t, testcase.retryable, IsRetryable(testcase.err))
+			assert.Equal(t, testcase.requiresUserInput, RequiresUserAction(testcase.err))
+		})
+	}
+}
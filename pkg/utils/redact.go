@@ -0,0 +1,90 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedValue replaces the value of secret-bearing fields in logs.
+const RedactedValue = "[REDACTED]"
+
+// sensitiveKeyParts are matched (case insensitive) against credential keys to decide if their value must be masked.
+var sensitiveKeyParts = []string{"APIKEY", "API_KEY", "PROFILEID", "PROFILE_ID", "SECRET", "TOKEN", "PASSWORD", "USERNAME"}
+
+// IsSensitiveKey reports whether the value stored under key, for example IBMCLOUD_APIKEY, must not be logged.
+func IsSensitiveKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns RedactedValue for a non empty value, so that logs still tell whether the value was set.
+func Redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// Credentials is a credentials map (for example read from ibm-credentials.env), which masks the secret-bearing
+// values when logged using zap.Object or zap.Any.
+type Credentials map[string]string
+
+// MarshalLogObject ...
+func (credentials Credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(credentials))
+	for key := range credentials {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := credentials[key]
+		if IsSensitiveKey(key) {
+			value = Redact(value)
+		}
+		enc.AddString(key, value)
+	}
+	return nil
+}
+
+// String masks the secret-bearing values, so that the credentials are not leaked when formatted using fmt.
+func (credentials Credentials) String() string {
+	keys := make([]string, 0, len(credentials))
+	for key := range credentials {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := credentials[key]
+		if IsSensitiveKey(key) {
+			value = Redact(value)
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	return "map[" + strings.Join(pairs, " ") + "]"
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	testcases := []struct {
+		key       string
+		sensitive bool
+	}{
+		{key: IBMCLOUD_APIKEY, sensitive: true},
+		{key: IBMCLOUD_PROFILEID, sensitive: true},
+		{key: "g2_api_key", sensitive: true},
+		{key: "iam_client_secret", sensitive: true},
+		{key: "refresh_token", sensitive: true},
+		{key: IBMCLOUD_AUTHTYPE, sensitive: false},
+		{key: "iam_url", sensitive: false},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.key, func(t *testing.T) {
+			assert.Equal(t, testcase.sensitive, IsSensitiveKey(testcase.key))
+		})
+	}
+}
+
+func TestCredentialsString(t *testing.T) {
+	credentials := Credentials{IBMCLOUD_AUTHTYPE: IAM, IBMCLOUD_APIKEY: "api-key", IBMCLOUD_PROFILEID: ""}
+	assert.Equal(t, "map[IBMCLOUD_APIKEY=[REDACTED] IBMCLOUD_AUTHTYPE=iam IBMCLOUD_PROFILEID=]", fmt.Sprintf("%v", credentials))
+	assert.Equal(t, "", Redact(""))
+}
@@ -0,0 +1,72 @@
+/**
+ * Copyright 2022 IBM Corp.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracerName is the name of the tracer creating the spans of the library.
+	TracerName = "github.com/IBM/secret-utils-lib"
+
+	// AttrAuthType is the type of credentials used by the authenticator (iam, pod-identity or DEFAULT).
+	AttrAuthType = attribute.Key("secret_utils.auth_type")
+	// AttrProvider is the provider the authenticator is initialized for.
+	AttrProvider = attribute.Key("secret_utils.provider")
+	// AttrEndpoint is the IAM endpoint a token is requested from.
+	AttrEndpoint = attribute.Key("secret_utils.endpoint")
+	// AttrAttempt is the attempt number (starting from 1) of a token request.
+	AttrAttempt = attribute.Key("secret_utils.attempt")
+	// AttrFallback is true if the token is requested from public IAM after the private endpoint timed out.
+	AttrFallback = attribute.Key("secret_utils.fallback")
+	// AttrCacheHit is true if the token in cache was returned.
+	AttrCacheHit = attribute.Key("secret_utils.cache_hit")
+	// AttrFreshTokenRequired is true if the caller asked for a fresh token.
+	AttrFreshTokenRequired = attribute.Key("secret_utils.fresh_token_required")
+	// AttrCredentialFormat is the format of the credentials parsed.
+	AttrCredentialFormat = attribute.Key("secret_utils.credential_format")
+	// AttrNamespace is the namespace of the k8s object read.
+	AttrNamespace = attribute.Key("k8s.namespace.name")
+	// AttrSecretName is the name of the k8s secret read, the data of the secret is never recorded.
+	AttrSecretName = attribute.Key("secret_utils.secret_name")
+	// AttrConfigMapName is the name of the k8s config map read.
+	AttrConfigMapName = attribute.Key("secret_utils.configmap_name")
+	// AttrKey is the key read from the secret or config map.
+	AttrKey = attribute.Key("secret_utils.key")
+)
+
+// StartSpan starts a span using the global tracer provider. Spans are recorded only if the application
+// sets a tracer provider using otel.SetTracerProvider, else they are no-op.
+// Attributes must never carry secret values such as api keys, profile IDs or tokens.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.GetTracerProvider().Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err in the span, if not nil, and ends the span.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
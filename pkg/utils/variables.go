@@ -18,6 +18,8 @@
 package utils
 
 var (
-	// TokenExpirydiff ...
+	// TokenExpirydiff is the minimum remaining lifetime (in seconds) of a token for token.CheckTokenLifeTime to accept it.
+	// It applies to every authenticator in the process which has no token lifetime policy set, prefer the
+	// TokenLifetimePolicy option of the authenticator.
 	TokenExpirydiff uint64
 )